@@ -3,25 +3,33 @@ package ratelimit
 import (
 	"context"
 	"google.golang.org/grpc"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
 type FixWindowLimiter struct {
-	// 窗口的起始时间
-	timestamp int64
+	// key => *fixWindow
+	windows sync.Map
+	// 上一次清理过期窗口的时间
+	lastSweep int64
 	// 窗口大小
 	interval int64
 	// 在这个窗口内，允许通过的最大请求数量
 	rate     int64
-	cnt      int64
 	onReject rejectStrategy
 }
 
+type fixWindow struct {
+	// 窗口的起始时间
+	timestamp int64
+	cnt       int64
+}
+
 func NewFixWindowLimiter(interval time.Duration, rate int64) *FixWindowLimiter {
 	return &FixWindowLimiter{
 		interval:  interval.Nanoseconds(),
-		timestamp: time.Now().UnixNano(),
+		lastSweep: time.Now().UnixNano(),
 		rate:      rate,
 		onReject:  defaultRejectStrategy,
 	}
@@ -29,18 +37,9 @@ func NewFixWindowLimiter(interval time.Duration, rate int64) *FixWindowLimiter {
 
 func (t *FixWindowLimiter) BuildServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		// 考虑 t.cnt 重置的问题
-		current := time.Now().UnixNano()
-		timestamp := atomic.LoadInt64(&t.timestamp)
-		cnt := atomic.LoadInt64(&t.cnt)
-		if timestamp+t.interval < current {
-			// 新窗口，需要重置窗口
-			if atomic.CompareAndSwapInt64(&t.timestamp, timestamp, current) {
-				atomic.CompareAndSwapInt64(&t.cnt, cnt, 0)
-			}
-		}
-		cnt = atomic.AddInt64(&t.cnt, 1)
-		if cnt > t.rate {
+		// 整个服务共用一个窗口
+		limited, _, _ := t.Limit(ctx, "")
+		if limited {
 			//err = errors.New("触发瓶颈了")
 			//return
 			return t.onReject(ctx, req, info, handler)
@@ -49,3 +48,44 @@ func (t *FixWindowLimiter) BuildServerInterceptor() grpc.UnaryServerInterceptor
 		return
 	}
 }
+
+// Limit 每个 key 有自己的窗口
+func (t *FixWindowLimiter) Limit(ctx context.Context, key string) (bool, time.Duration, error) {
+	current := time.Now().UnixNano()
+	t.sweep(current)
+	val, ok := t.windows.Load(key)
+	if !ok {
+		val, _ = t.windows.LoadOrStore(key, &fixWindow{timestamp: current})
+	}
+	w := val.(*fixWindow)
+	// 考虑 w.cnt 重置的问题
+	timestamp := atomic.LoadInt64(&w.timestamp)
+	cnt := atomic.LoadInt64(&w.cnt)
+	if timestamp+t.interval < current {
+		// 新窗口，需要重置窗口
+		if atomic.CompareAndSwapInt64(&w.timestamp, timestamp, current) {
+			atomic.CompareAndSwapInt64(&w.cnt, cnt, 0)
+		}
+		timestamp = atomic.LoadInt64(&w.timestamp)
+	}
+	if atomic.AddInt64(&w.cnt, 1) > t.rate {
+		// 等到下一个窗口
+		return true, time.Duration(timestamp + t.interval - current), nil
+	}
+	return false, 0, nil
+}
+
+// sweep 每隔一个窗口删除一次过期的窗口
+// 按照 IP 之类的 key 限流的时候，key 的数量没有上限，不删除的话内存会一直增长
+func (t *FixWindowLimiter) sweep(current int64) {
+	last := atomic.LoadInt64(&t.lastSweep)
+	if current-last < t.interval || !atomic.CompareAndSwapInt64(&t.lastSweep, last, current) {
+		return
+	}
+	t.windows.Range(func(key, val any) bool {
+		if atomic.LoadInt64(&val.(*fixWindow).timestamp)+t.interval < current {
+			t.windows.Delete(key)
+		}
+		return true
+	})
+}
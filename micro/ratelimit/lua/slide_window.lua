@@ -1,26 +1,28 @@
--- 1, 2, 3, 4, 5, 6, 7 这是你的元素
--- ZREMRANGEBYSCORE key1 0 6
--- 7 执行完之后
-
--- 限流对象
-local key = KEYS[1]
--- 窗口大小
-local window = tonumber(ARGV[1])
--- 阈值
-local threshold = tonumber( ARGV[2])
-local now = tonumber(ARGV[3])
--- 窗口的起始时间
-local min = now - window
-
-redis.call('ZREMRANGEBYSCORE', key, '-inf', min)
-local cnt = redis.call('ZCOUNT', key, '-inf', '+inf')
--- local cnt = redis.call('ZCOUNT', key, min, '+inf')
-if cnt >= threshold then
-    -- 执行限流
-    return "true"
-else
-    -- 把 score 和 member 都设置成 now
-    redis.call('ZADD', key, now, now)
-    redis.call('PEXPIRE', key, window)
-    return "false"
+-- 1, 2, 3, 4, 5, 6, 7 这是你的元素
+-- ZREMRANGEBYSCORE key1 0 6
+-- 7 执行完之后
+
+-- 限流对象
+local key = KEYS[1]
+-- 窗口大小
+local window = tonumber(ARGV[1])
+-- 阈值
+local threshold = tonumber( ARGV[2])
+local now = tonumber(ARGV[3])
+-- 这一次请求的唯一标识，同一毫秒内可能有多个请求
+local member = ARGV[4]
+-- 窗口的起始时间
+local min = now - window
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', min)
+local cnt = redis.call('ZCOUNT', key, '-inf', '+inf')
+-- local cnt = redis.call('ZCOUNT', key, min, '+inf')
+if cnt >= threshold then
+    -- 执行限流
+    return "true"
+else
+    -- score 是 now，用于移出窗口
+    redis.call('ZADD', key, now, member)
+    redis.call('PEXPIRE', key, window)
+    return "false"
 end
\ No newline at end of file
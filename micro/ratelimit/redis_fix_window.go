@@ -38,7 +38,7 @@ func (t *RedisFixWindowLimiter) BuildServerInterceptor() grpc.UnaryServerInterce
 		// 使用 FullMethod，那就是单一方法上限流，比如说 GetById
 		// 使用服务名来限流，那就是在单一服务上 users.UserService
 		// 使用应用名，user-service
-		limit, err := t.limit(ctx)
+		limit, err := t.limit(ctx, t.service)
 		//ctx = context.WithValue(ctx, "limit", true)
 		if err != nil {
 			return
@@ -53,7 +53,31 @@ func (t *RedisFixWindowLimiter) BuildServerInterceptor() grpc.UnaryServerInterce
 	}
 }
 
-func (t *RedisFixWindowLimiter) limit(ctx context.Context) (bool, error) {
-	return t.client.Eval(ctx, luaFixWindow, []string{t.service},
+// Limit 每个 key 在 Redis 里面有自己的计数，Redis 的 key 是 service:key
+func (t *RedisFixWindowLimiter) Limit(ctx context.Context, key string) (bool, time.Duration, error) {
+	key = redisKey(t.service, key)
+	limited, err := t.limit(ctx, key)
+	if err != nil || !limited {
+		return false, 0, err
+	}
+	// 等到窗口过期，也就是 key 过期
+	ttl, err := t.client.PTTL(ctx, key).Result()
+	if err != nil || ttl <= 0 {
+		// 限流的结果已经有了，拿不到过期时间就等一个窗口
+		return true, t.interval, nil
+	}
+	return true, ttl, nil
+}
+
+func (t *RedisFixWindowLimiter) limit(ctx context.Context, key string) (bool, error) {
+	return t.client.Eval(ctx, luaFixWindow, []string{key},
 		t.interval.Milliseconds(), t.rate).Bool()
 }
+
+// redisKey 空字符串代表整个服务共用一个计数，key 就是服务名
+func redisKey(service string, key string) string {
+	if key == "" {
+		return service
+	}
+	return service + ":" + key
+}
@@ -131,7 +131,7 @@ func TestFixWindowLimiter_LimitUnary(t *testing.T) {
 			tc.before(t)
 			defer tc.after(t)
 			l := NewRedisFixWindowLimiter(rdb, tc.key, tc.interval, tc.rate)
-			limit, err := l.limit(context.Background())
+			limit, err := l.limit(context.Background(), tc.key)
 			assert.Equal(t, tc.wantErr, err)
 			if err != nil {
 				return
@@ -6,10 +6,12 @@ import (
 	"errors"
 	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
+	"math/rand"
+	"strconv"
 	"time"
 )
 
-//go:embed lua/fix_window.lua
+//go:embed lua/slide_window.lua
 var luaSlideWindow string
 
 type RedisSlideWindowLimiter struct {
@@ -38,7 +40,7 @@ func (t *RedisSlideWindowLimiter) BuildServerInterceptor() grpc.UnaryServerInter
 		// 使用 FullMethod，那就是单一方法上限流，比如说 GetById
 		// 使用服务名来限流，那就是在单一服务上 users.UserService
 		// 使用应用名，user-service
-		limit, err := t.limit(ctx)
+		limit, err := t.limit(ctx, t.service)
 		//ctx = context.WithValue(ctx, "limit", true)
 		if err != nil {
 			return
@@ -53,7 +55,34 @@ func (t *RedisSlideWindowLimiter) BuildServerInterceptor() grpc.UnaryServerInter
 	}
 }
 
-func (t *RedisSlideWindowLimiter) limit(ctx context.Context) (bool, error) {
-	return t.client.Eval(ctx, luaSlideWindow, []string{t.service},
-		t.interval.Milliseconds(), t.rate, time.Now().UnixMilli()).Bool()
+// Limit 每个 key 在 Redis 里面有自己的窗口，Redis 的 key 是 service:key
+func (t *RedisSlideWindowLimiter) Limit(ctx context.Context, key string) (bool, time.Duration, error) {
+	key = redisKey(t.service, key)
+	limited, err := t.limit(ctx, key)
+	if err != nil || !limited {
+		return false, 0, err
+	}
+	// 等到最早的请求移出窗口，score 是请求的时间戳，单位是毫秒
+	oldest, err := t.client.ZRangeWithScores(ctx, key, 0, 0).Result()
+	if err != nil || len(oldest) == 0 {
+		// 限流的结果已经有了，拿不到最早的请求就等一个窗口
+		return true, t.interval, nil
+	}
+	retryAfter := time.UnixMilli(int64(oldest[0].Score)).Add(t.interval).Sub(time.Now())
+	if retryAfter <= 0 {
+		retryAfter = time.Millisecond
+	}
+	return true, retryAfter, nil
+}
+
+func (t *RedisSlideWindowLimiter) limit(ctx context.Context, key string) (bool, error) {
+	return t.limitAt(ctx, key, time.Now())
+}
+
+func (t *RedisSlideWindowLimiter) limitAt(ctx context.Context, key string, now time.Time) (bool, error) {
+	ms := now.UnixMilli()
+	// member 不能只用时间戳，否则同一毫秒内的请求会合并成一个，突发流量就绕过了限流
+	member := strconv.FormatInt(ms, 10) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+	return t.client.Eval(ctx, luaSlideWindow, []string{key},
+		t.interval.Milliseconds(), t.rate, ms, member).Bool()
 }
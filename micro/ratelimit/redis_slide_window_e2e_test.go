@@ -38,3 +38,37 @@ func TestRedisSlideWindowLimiter_e2e_BuildServerInterceptor(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, &gen.GetByIdResp{}, resp)
 }
+
+func TestRedisSlideWindowLimiter_limitAt(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	key := "slide-window-burst"
+	_, err := rdb.Del(context.Background(), key).Result()
+	require.NoError(t, err)
+	defer rdb.Del(context.Background(), key)
+
+	// 一分钟三个请求，五个请求在同一毫秒内到达
+	l := NewRedisSlideWindowLimiter(rdb, key, time.Minute, 3)
+	now := time.Now()
+	var limits []bool
+	for i := 0; i < 5; i++ {
+		limit, er := l.limitAt(context.Background(), key, now)
+		require.NoError(t, er)
+		limits = append(limits, limit)
+	}
+	assert.Equal(t, []bool{false, false, false, true, true}, limits)
+
+	// 使用的是滑动窗口的脚本，每个请求是有序集合里面的一个元素
+	typ, err := rdb.Type(context.Background(), key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "zset", typ)
+	cnt, err := rdb.ZCard(context.Background(), key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), cnt)
+
+	// 一个窗口之后，最早的请求都移出了窗口
+	limit, err := l.limitAt(context.Background(), key, now.Add(time.Minute+time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, limit)
+}
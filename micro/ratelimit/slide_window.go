@@ -10,52 +10,85 @@ import (
 )
 
 type SlideWindowLimiter struct {
-	queue    *list.List
-	interval int64
-	rate     int
-	mutex    sync.Mutex
+	// key => 窗口内的请求的时间戳
+	queues map[string]*list.List
+	// 上一次清理空窗口的时间
+	lastSweep int64
+	interval  int64
+	rate      int
+	mutex     sync.Mutex
 }
 
 func NewSlideWindowLimiter(interval time.Duration, rate int) *SlideWindowLimiter {
 	return &SlideWindowLimiter{
-		queue:    list.New(),
-		interval: interval.Nanoseconds(),
-		rate:     rate,
+		queues:    make(map[string]*list.List),
+		lastSweep: time.Now().UnixNano(),
+		interval:  interval.Nanoseconds(),
+		rate:      rate,
 	}
 }
 
 func (t *SlideWindowLimiter) BuildServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		now := time.Now().UnixNano()
-		boundary := now - t.interval
-
-		// 快路径
-		t.mutex.Lock()
-		length := t.queue.Len()
-		if length < t.rate {
-			resp, err = handler(ctx, req)
-			// 记住了请求的时间戳
-			t.queue.PushBack(now)
-			t.mutex.Unlock()
-			return
-		}
-
-		// 慢路径
-		timestamp := t.queue.Front()
-		// 这个循环把所有不在窗口内的数据都删掉了
-		for timestamp != nil && timestamp.Value.(int64) < boundary {
-			t.queue.Remove(timestamp)
-			timestamp = t.queue.Front()
-		}
-		length = t.queue.Len()
-		t.mutex.Unlock()
-		if length >= t.rate {
+		// 整个服务共用一个窗口
+		limited, _, _ := t.Limit(ctx, "")
+		if limited {
 			err = errors.New("到达瓶颈")
 			return
 		}
 		resp, err = handler(ctx, req)
+		return
+	}
+}
+
+// Limit 每个 key 有自己的窗口
+func (t *SlideWindowLimiter) Limit(ctx context.Context, key string) (bool, time.Duration, error) {
+	now := time.Now().UnixNano()
+	boundary := now - t.interval
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sweep(now, boundary)
+	queue, ok := t.queues[key]
+	if !ok {
+		queue = list.New()
+		t.queues[key] = queue
+	}
+	// 快路径
+	if queue.Len() < t.rate {
 		// 记住了请求的时间戳
-		t.queue.PushBack(now)
+		queue.PushBack(now)
+		return false, 0, nil
+	}
+
+	// 慢路径
+	timestamp := queue.Front()
+	// 这个循环把所有不在窗口内的数据都删掉了
+	for timestamp != nil && timestamp.Value.(int64) < boundary {
+		queue.Remove(timestamp)
+		timestamp = queue.Front()
+	}
+	if queue.Len() >= t.rate {
+		// 等到最早的请求移出窗口
+		retryAfter := time.Duration(t.interval)
+		if timestamp != nil {
+			retryAfter = time.Duration(timestamp.Value.(int64) - boundary)
+		}
+		return true, retryAfter, nil
+	}
+	queue.PushBack(now)
+	return false, 0, nil
+}
+
+// sweep 每隔一个窗口删除一次所有请求都已经移出窗口的 key，调用方需要持有锁
+func (t *SlideWindowLimiter) sweep(now int64, boundary int64) {
+	if now-t.lastSweep < t.interval {
 		return
 	}
+	t.lastSweep = now
+	for key, queue := range t.queues {
+		if last := queue.Back(); last == nil || last.Value.(int64) < boundary {
+			delete(t.queues, key)
+		}
+	}
 }
@@ -33,3 +33,41 @@ func TestSlideWindowBuildInterceptor(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, &gen.GetByIdResp{}, resp)
 }
+
+func TestSlideWindowLimiter_Limit(t *testing.T) {
+	// 一分钟只能有两个请求
+	l := NewSlideWindowLimiter(time.Minute, 2)
+	for i := 0; i < 2; i++ {
+		limited, _, err := l.Limit(context.Background(), "127.0.0.1")
+		require.NoError(t, err)
+		assert.False(t, limited)
+	}
+	limited, retryAfter, err := l.Limit(context.Background(), "127.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, limited)
+	assert.True(t, retryAfter > 0 && retryAfter <= time.Minute)
+
+	// 不同的 key 各自计数
+	limited, _, err = l.Limit(context.Background(), "127.0.0.2")
+	require.NoError(t, err)
+	assert.False(t, limited)
+
+	// 阈值为 0 的时候全部限流
+	limited, retryAfter, err = NewSlideWindowLimiter(time.Minute, 0).Limit(context.Background(), "")
+	require.NoError(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, time.Minute, retryAfter)
+}
+
+func TestSlideWindowLimiter_sweep(t *testing.T) {
+	l := NewSlideWindowLimiter(time.Millisecond*10, 1)
+	_, _, err := l.Limit(context.Background(), "127.0.0.1")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 30)
+	// 这一次请求会顺便删掉所有请求都移出了窗口的 key
+	limited, _, err := l.Limit(context.Background(), "127.0.0.2")
+	require.NoError(t, err)
+	assert.False(t, limited)
+	_, ok := l.queues["127.0.0.1"]
+	assert.False(t, ok)
+}
@@ -4,12 +4,30 @@ import (
 	"context"
 	"errors"
 	"google.golang.org/grpc"
+	"sync"
 	"time"
 )
 
 type TokenBucketLimiter struct {
+	// BuildServerInterceptor 使用的令牌桶，拿不到令牌的时候会等待
 	tokens chan struct{}
 	close  chan struct{}
+
+	// Limit 使用的令牌桶，每个 key 一个，拿不到令牌的时候直接限流
+	// 令牌在拿的时候按照经过的时间补充，所以不需要额外的 goroutine
+	capacity int
+	interval int64
+	// key => *tokenBucket
+	buckets map[string]*tokenBucket
+	// 上一次清理满了的令牌桶的时间
+	lastSweep int64
+	mutex     sync.Mutex
+}
+
+type tokenBucket struct {
+	tokens int
+	// 上一次补充令牌的时间
+	timestamp int64
 }
 
 // NewTokenBucketLimiter 创建一个 TokenBucketLimiter
@@ -35,8 +53,12 @@ func NewTokenBucketLimiter(capacity int, interval time.Duration) *TokenBucketLim
 		}
 	}()
 	return &TokenBucketLimiter{
-		tokens: ch,
-		close:  closeCh,
+		tokens:    ch,
+		close:     closeCh,
+		capacity:  capacity,
+		interval:  interval.Nanoseconds(),
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now().UnixNano(),
 	}
 }
 
@@ -60,6 +82,65 @@ func (t *TokenBucketLimiter) BuildServerInterceptor() grpc.UnaryServerIntercepto
 	}
 }
 
+// Limit 每个 key 有自己的令牌桶，新的令牌桶是满的
+// 和 BuildServerInterceptor 不一样，拿不到令牌的时候不会等待，
+// 因为 HTTP 的客户端更适合根据 Retry-After 重试
+func (t *TokenBucketLimiter) Limit(ctx context.Context, key string) (bool, time.Duration, error) {
+	if t.capacity <= 0 || t.interval <= 0 {
+		return true, time.Duration(t.interval), nil
+	}
+	now := time.Now().UnixNano()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sweep(now)
+	b, ok := t.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: t.capacity, timestamp: now}
+		if t.buckets == nil {
+			t.buckets = make(map[string]*tokenBucket)
+		}
+		t.buckets[key] = b
+	}
+	t.refill(b, now)
+	if b.tokens == 0 {
+		// 等到下一个令牌
+		return true, time.Duration(b.timestamp + t.interval - now), nil
+	}
+	b.tokens--
+	return false, 0, nil
+}
+
+// refill 补充上一次补充之后产生的令牌
+func (t *TokenBucketLimiter) refill(b *tokenBucket, now int64) {
+	n := (now - b.timestamp) / t.interval
+	if n == 0 {
+		return
+	}
+	b.timestamp += n * t.interval
+	if int64(t.capacity-b.tokens) <= n {
+		// 满了之后不再产生令牌，从现在开始重新计时
+		b.tokens = t.capacity
+		b.timestamp = now
+		return
+	}
+	b.tokens += int(n)
+}
+
+// sweep 每隔装满一个令牌桶的时间，删除一次满了的令牌桶，调用方需要持有锁
+// 满了的令牌桶和新建的令牌桶没有区别，删掉可以避免按照 IP 之类的 key 限流的时候内存一直增长
+func (t *TokenBucketLimiter) sweep(now int64) {
+	if now-t.lastSweep < t.interval*int64(t.capacity) {
+		return
+	}
+	t.lastSweep = now
+	for key, b := range t.buckets {
+		t.refill(b, now)
+		if b.tokens == t.capacity {
+			delete(t.buckets, key)
+		}
+	}
+}
+
 func (t *TokenBucketLimiter) Close() error {
 	close(t.close)
 	return nil
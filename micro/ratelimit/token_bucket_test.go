@@ -105,3 +105,96 @@ func TestTokenBucketLimiter_Tokens(t *testing.T) {
 	require.Equal(t, context.DeadlineExceeded, err)
 	require.Nil(t, resp)
 }
+
+func TestTokenBucketLimiter_Limit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		capacity int
+		interval time.Duration
+		// 在这之前已经拿走的令牌
+		taken int
+
+		wantLimited bool
+	}{
+		{
+			name:     "new bucket is full",
+			capacity: 2,
+			interval: time.Minute,
+		},
+		{
+			name:     "last token",
+			capacity: 2,
+			interval: time.Minute,
+			taken:    1,
+		},
+		{
+			name:        "no token",
+			capacity:    2,
+			interval:    time.Minute,
+			taken:       2,
+			wantLimited: true,
+		},
+		{
+			name:        "zero capacity",
+			capacity:    0,
+			interval:    time.Minute,
+			wantLimited: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewTokenBucketLimiter(tc.capacity, tc.interval)
+			defer l.Close()
+			for i := 0; i < tc.taken; i++ {
+				_, _, err := l.Limit(context.Background(), "127.0.0.1")
+				require.NoError(t, err)
+			}
+			limited, retryAfter, err := l.Limit(context.Background(), "127.0.0.1")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantLimited, limited)
+			if limited {
+				assert.True(t, retryAfter > 0 && retryAfter <= tc.interval)
+			}
+			// 不同的 key 有自己的令牌桶
+			limited, _, err = l.Limit(context.Background(), "127.0.0.2")
+			require.NoError(t, err)
+			assert.Equal(t, tc.capacity == 0, limited)
+		})
+	}
+}
+
+func TestTokenBucketLimiter_refill(t *testing.T) {
+	l := &TokenBucketLimiter{capacity: 3, interval: 10}
+	testCases := []struct {
+		name   string
+		bucket *tokenBucket
+		now    int64
+
+		wantBucket *tokenBucket
+	}{
+		{
+			name:       "not yet",
+			bucket:     &tokenBucket{tokens: 0, timestamp: 100},
+			now:        109,
+			wantBucket: &tokenBucket{tokens: 0, timestamp: 100},
+		},
+		{
+			name:       "two tokens",
+			bucket:     &tokenBucket{tokens: 0, timestamp: 100},
+			now:        125,
+			wantBucket: &tokenBucket{tokens: 2, timestamp: 120},
+		},
+		{
+			name:       "full",
+			bucket:     &tokenBucket{tokens: 1, timestamp: 100},
+			now:        155,
+			wantBucket: &tokenBucket{tokens: 3, timestamp: 155},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l.refill(tc.bucket, tc.now)
+			assert.Equal(t, tc.wantBucket, tc.bucket)
+		})
+	}
+}
@@ -3,6 +3,24 @@ package ratelimit
 import (
 	"context"
 	"google.golang.org/grpc"
+	"time"
+)
+
+// Limiter 限流算法，和协议无关
+// gRPC 的拦截器和 web 的 Middleware 都基于它，这样 HTTP 和 gRPC 使用同一套限流的实现
+type Limiter interface {
+	// Limit 判断 key 的这一次请求要不要限流，返回 true 代表要限流
+	// key 是限流对象，例如服务名、IP 或者路由，不同的 key 各自计数
+	// retryAfter 是建议多久之后重试，只有限流的时候才有意义
+	Limit(ctx context.Context, key string) (limited bool, retryAfter time.Duration, err error)
+}
+
+var (
+	_ Limiter = &FixWindowLimiter{}
+	_ Limiter = &SlideWindowLimiter{}
+	_ Limiter = &TokenBucketLimiter{}
+	_ Limiter = &RedisFixWindowLimiter{}
+	_ Limiter = &RedisSlideWindowLimiter{}
 )
 
 type rejectStrategy func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error)
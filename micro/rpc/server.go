@@ -6,6 +6,7 @@ import (
 	"leanring-go/micro/rpc/message"
 	"leanring-go/micro/rpc/serialize"
 	"leanring-go/micro/rpc/serialize/json"
+	"leanring-go/validate"
 	"net"
 	"reflect"
 	"strconv"
@@ -15,14 +16,29 @@ import (
 type Server struct {
 	services    map[string]reflectionStub
 	serializers map[uint8]serialize.Serializer
+	// 解码请求之后校验请求，为 nil 的时候不校验
+	validator validate.Validator
 }
 
-func NewServer() *Server {
+type ServerOption func(server *Server)
+
+// ServerWithValidator 在调用服务之前校验请求，
+// 例如使用 validate.NewTagValidator() 根据 validate 标签校验
+func ServerWithValidator(v validate.Validator) ServerOption {
+	return func(server *Server) {
+		server.validator = v
+	}
+}
+
+func NewServer(opts ...ServerOption) *Server {
 	res := &Server{
 		services:    make(map[string]reflectionStub, 16),
 		serializers: make(map[uint8]serialize.Serializer, 4),
 	}
 	res.RegisterSerializer(&json.Serializer{})
+	for _, opt := range opts {
+		opt(res)
+	}
 	return res
 }
 
@@ -35,6 +51,7 @@ func (s *Server) RegisterService(service Service) {
 		s:           service,
 		value:       reflect.ValueOf(service),
 		serializers: s.serializers,
+		validator:   s.validator,
 	}
 }
 
@@ -132,6 +149,7 @@ type reflectionStub struct {
 	s           Service
 	value       reflect.Value
 	serializers map[uint8]serialize.Serializer
+	validator   validate.Validator
 }
 
 func (s *reflectionStub) invoke(ctx context.Context, req *message.Request) ([]byte, error) {
@@ -149,6 +167,12 @@ func (s *reflectionStub) invoke(ctx context.Context, req *message.Request) ([]by
 	if err != nil {
 		return nil, err
 	}
+	if s.validator != nil {
+		// 请求不合法，不需要调用服务
+		if err = s.validator.Validate(inReq.Interface()); err != nil {
+			return nil, err
+		}
+	}
 	in[1] = inReq
 	results := method.Call(in)
 
@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"leanring-go/micro/rpc/message"
+	"leanring-go/validate"
+	"testing"
+)
+
+type CreateUserReq struct {
+	Name string `json:"name" validate:"required,max=8"`
+}
+
+type CreateUserResp struct {
+	Msg string
+}
+
+type validateService struct{}
+
+func (v *validateService) Name() string {
+	return "validate-service"
+}
+
+func (v *validateService) CreateUser(ctx context.Context, req *CreateUserReq) (*CreateUserResp, error) {
+	return &CreateUserResp{Msg: "hello, " + req.Name}, nil
+}
+
+func TestServer_InvokeWithValidator(t *testing.T) {
+	server := NewServer(ServerWithValidator(validate.NewTagValidator()))
+	server.RegisterService(&validateService{})
+
+	testCases := []struct {
+		name     string
+		data     string
+		wantData string
+		wantErr  error
+	}{
+		{
+			name:     "valid",
+			data:     `{"name":"Tom"}`,
+			wantData: `{"Msg":"hello, Tom"}`,
+		},
+		{
+			name:    "invalid",
+			data:    `{"name":""}`,
+			wantErr: errors.New("validate: name 不能为空"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := server.Invoke(context.Background(), &message.Request{
+				ServiceName: "validate-service",
+				MethodName:  "CreateUser",
+				Serializer:  1,
+				Data:        []byte(tc.data),
+			})
+			if tc.wantErr != nil {
+				require.Error(t, err)
+				assert.Equal(t, tc.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantData, string(resp.Data))
+		})
+	}
+}
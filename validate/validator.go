@@ -0,0 +1,338 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Validator 校验请求
+// web 在绑定请求之后调用，rpc 的服务端在解码请求之后调用
+type Validator interface {
+	// Validate 校验 val，val 一般是结构体指针
+	// 校验不通过的时候返回 ValidationErrors，其余的 error 代表规则本身有问题
+	Validate(val any) error
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	// Field 字段名，有 json 标签的使用 json 标签里面的名字
+	// 嵌套结构体的字段用 . 连接，例如 address.city
+	Field string `json:"field"`
+	// Rule 没有通过的规则，例如 required、min
+	Rule string `json:"rule"`
+	// Param 规则的参数，例如 min=1 里面的 1
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors 所有没有通过校验的字段
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Message)
+	}
+	return "validate: " + strings.Join(msgs, "; ")
+}
+
+// RuleFunc 校验规则，返回 false 代表没有通过
+// val 是字段的值，指针已经解引用了
+// param 是规则的参数，没有参数的时候为空字符串
+type RuleFunc func(val reflect.Value, param string) bool
+
+// TagValidator 根据结构体标签校验，例如 `validate:"required,min=1,max=64"`
+// 内置的规则有：
+// required 不能是零值，字符串、切片和 map 不能为空
+// omitempty 零值的时候跳过其余的规则
+// min、max 数字比较大小，字符串比较字符个数，切片、数组和 map 比较长度
+// email 合法的邮箱地址
+// oneof 必须是用空格分隔的值中的一个，例如 oneof=a b
+// 结构体字段和结构体指针字段会递归校验
+type TagValidator struct {
+	tagName string
+	rules   map[string]RuleFunc
+	// reflect.Type => []*fieldRules
+	cache sync.Map
+}
+
+// TagValidatorOption Option 模式，用于定制 TagValidator
+type TagValidatorOption func(v *TagValidator)
+
+// TagValidatorWithTagName 使用 validate 之外的标签
+func TagValidatorWithTagName(tagName string) TagValidatorOption {
+	return func(v *TagValidator) {
+		v.tagName = tagName
+	}
+}
+
+func NewTagValidator(opts ...TagValidatorOption) *TagValidator {
+	res := &TagValidator{
+		tagName: "validate",
+		rules: map[string]RuleFunc{
+			"min":   minRule,
+			"max":   maxRule,
+			"email": emailRule,
+			"oneof": oneofRule,
+		},
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// RegisterRule 注册自定义的规则，同名的规则会被覆盖
+// 需要在使用之前注册，RegisterRule 不是并发安全的
+func (v *TagValidator) RegisterRule(name string, rule RuleFunc) {
+	v.rules[name] = rule
+}
+
+// Validate 校验结构体或者结构体指针，其余类型的值直接通过
+func (v *TagValidator) Validate(val any) error {
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	if err := v.validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *TagValidator) validateStruct(rv reflect.Value, prefix string, errs *ValidationErrors) error {
+	fields, err := v.fieldsOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, fd := range fields {
+		fv := rv.Field(fd.index)
+		name := prefix + fd.name
+		isNil := false
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				isNil = true
+				break
+			}
+			fv = fv.Elem()
+		}
+		if isNil || isEmpty(fv) {
+			if fd.required {
+				*errs = append(*errs, FieldError{
+					Field:   name,
+					Rule:    "required",
+					Message: fmt.Sprintf("%s 不能为空", name),
+				})
+				continue
+			}
+			if isNil || fd.omitempty {
+				continue
+			}
+		}
+		for _, r := range fd.rules {
+			if !r.fn(fv, r.param) {
+				*errs = append(*errs, FieldError{
+					Field:   name,
+					Rule:    r.name,
+					Param:   r.param,
+					Message: message(name, r.name, r.param, fv),
+				})
+			}
+		}
+		if fv.Kind() == reflect.Struct {
+			if err = v.validateStruct(fv, name+".", errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type fieldRules struct {
+	index     int
+	name      string
+	required  bool
+	omitempty bool
+	rules     []rule
+}
+
+type rule struct {
+	name  string
+	param string
+	fn    RuleFunc
+}
+
+// fieldsOf 解析结构体的标签，结果按照类型缓存起来
+func (v *TagValidator) fieldsOf(typ reflect.Type) ([]*fieldRules, error) {
+	if res, ok := v.cache.Load(typ); ok {
+		return res.([]*fieldRules), nil
+	}
+	res := make([]*fieldRules, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+		if !fd.IsExported() {
+			continue
+		}
+		fr := &fieldRules{
+			index: i,
+			name:  fieldName(fd),
+		}
+		tag := fd.Tag.Get(v.tagName)
+		if tag == "-" {
+			continue
+		}
+		if tag != "" {
+			for _, item := range strings.Split(tag, ",") {
+				name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+				switch name {
+				case "required":
+					fr.required = true
+				case "omitempty":
+					fr.omitempty = true
+				default:
+					fn, ok := v.rules[name]
+					if !ok {
+						return nil, fmt.Errorf("validate: %s.%s 未知的规则 %s", typ.Name(), fd.Name, name)
+					}
+					if err := checkParam(name, param); err != nil {
+						return nil, fmt.Errorf("validate: %s.%s %w", typ.Name(), fd.Name, err)
+					}
+					fr.rules = append(fr.rules, rule{name: name, param: param, fn: fn})
+				}
+			}
+		}
+		fdType := fd.Type
+		for fdType.Kind() == reflect.Pointer {
+			fdType = fdType.Elem()
+		}
+		// 没有规则也没有嵌套结构体的字段不需要校验
+		if tag == "" && fdType.Kind() != reflect.Struct {
+			continue
+		}
+		res = append(res, fr)
+	}
+	v.cache.Store(typ, res)
+	return res, nil
+}
+
+// fieldName 优先使用 json 标签里面的名字，因为错误是返回给调用方看的
+func fieldName(fd reflect.StructField) string {
+	name, _, _ := strings.Cut(fd.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return fd.Name
+	}
+	return name
+}
+
+// checkParam 在解析标签的时候检查内置规则的参数，避免校验的时候才发现
+func checkParam(name string, param string) error {
+	switch name {
+	case "min", "max":
+		if _, err := strconv.ParseFloat(param, 64); err != nil {
+			return fmt.Errorf("规则 %s 的参数 %s 不是数字", name, param)
+		}
+	case "oneof":
+		if strings.TrimSpace(param) == "" {
+			return fmt.Errorf("规则 %s 缺少参数", name)
+		}
+	}
+	return nil
+}
+
+func isEmpty(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return val.Len() == 0
+	default:
+		return val.IsZero()
+	}
+}
+
+// size 数字返回它的值，字符串返回字符个数，切片、数组和 map 返回长度
+// 第二个返回值代表 val 是不是数字
+func size(val reflect.Value) (float64, bool) {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(val.String())), false
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(val.Len()), false
+	default:
+		return 0, false
+	}
+}
+
+func minRule(val reflect.Value, param string) bool {
+	limit, _ := strconv.ParseFloat(param, 64)
+	s, _ := size(val)
+	return s >= limit
+}
+
+func maxRule(val reflect.Value, param string) bool {
+	limit, _ := strconv.ParseFloat(param, 64)
+	s, _ := size(val)
+	return s <= limit
+}
+
+func emailRule(val reflect.Value, _ string) bool {
+	if val.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(val.String())
+	// Tom <tom@example.com> 这种带名字的格式不算
+	return err == nil && addr.Address == val.String()
+}
+
+func oneofRule(val reflect.Value, param string) bool {
+	str := fmt.Sprint(val.Interface())
+	for _, option := range strings.Fields(param) {
+		if option == str {
+			return true
+		}
+	}
+	return false
+}
+
+func message(field string, ruleName string, param string, val reflect.Value) string {
+	switch ruleName {
+	case "min", "max":
+		cmp := "小于"
+		if ruleName == "max" {
+			cmp = "大于"
+		}
+		if _, isNumber := size(val); isNumber {
+			return fmt.Sprintf("%s 不能%s %s", field, cmp, param)
+		}
+		return fmt.Sprintf("%s 的长度不能%s %s", field, cmp, param)
+	case "email":
+		return fmt.Sprintf("%s 不是合法的邮箱地址", field)
+	case "oneof":
+		return fmt.Sprintf("%s 必须是 [%s] 中的一个", field, param)
+	default:
+		return fmt.Sprintf("%s 没有通过 %s 校验", field, ruleName)
+	}
+}
@@ -0,0 +1,131 @@
+package validate
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type User struct {
+	Name     string   `json:"name" validate:"required,min=1,max=8"`
+	Email    string   `json:"email" validate:"omitempty,email"`
+	Age      int      `validate:"min=18,max=120"`
+	Role     string   `json:"role" validate:"oneof=admin user"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Address  Address  `json:"address"`
+	Backup   *Address `json:"backup"`
+	Nickname *string  `json:"nickname" validate:"required"`
+	ignored  string   `validate:"required"`
+}
+
+func TestTagValidator_Validate(t *testing.T) {
+	nickname := "tom"
+	testCases := []struct {
+		name    string
+		val     any
+		wantErr error
+	}{
+		{
+			name: "valid",
+			val: &User{
+				Name:     "Tom",
+				Email:    "tom@example.com",
+				Age:      18,
+				Role:     "admin",
+				Address:  Address{City: "Shenzhen"},
+				Nickname: &nickname,
+			},
+		},
+		{
+			name: "invalid",
+			val: &User{
+				Name:    "汤姆汤姆汤姆汤姆汤姆",
+				Email:   "Tom <tom@example.com>",
+				Age:     17,
+				Role:    "root",
+				Tags:    []string{"a", "b", "c"},
+				Backup:  &Address{},
+				ignored: "",
+			},
+			wantErr: ValidationErrors{
+				{Field: "name", Rule: "max", Param: "8", Message: "name 的长度不能大于 8"},
+				{Field: "email", Rule: "email", Message: "email 不是合法的邮箱地址"},
+				{Field: "Age", Rule: "min", Param: "18", Message: "Age 不能小于 18"},
+				{Field: "role", Rule: "oneof", Param: "admin user", Message: "role 必须是 [admin user] 中的一个"},
+				{Field: "tags", Rule: "max", Param: "2", Message: "tags 的长度不能大于 2"},
+				{Field: "address.city", Rule: "required", Message: "address.city 不能为空"},
+				{Field: "backup.city", Rule: "required", Message: "backup.city 不能为空"},
+				{Field: "nickname", Rule: "required", Message: "nickname 不能为空"},
+			},
+		},
+		{
+			// 只有 required 失败的时候不再检查别的规则
+			name: "required",
+			val: User{
+				Age:      18,
+				Role:     "user",
+				Address:  Address{City: "Shenzhen"},
+				Nickname: &nickname,
+			},
+			wantErr: ValidationErrors{
+				{Field: "name", Rule: "required", Message: "name 不能为空"},
+			},
+		},
+		{
+			name: "not struct",
+			val:  map[string]string{},
+		},
+		{
+			name: "nil pointer",
+			val:  (*User)(nil),
+		},
+		{
+			name: "unknown rule",
+			val: &struct {
+				Name string `validate:"abc"`
+			}{},
+			wantErr: errors.New("validate: .Name 未知的规则 abc"),
+		},
+		{
+			name: "invalid param",
+			val: &struct {
+				Name string `validate:"min=a"`
+			}{},
+			wantErr: errors.New("validate: .Name 规则 min 的参数 a 不是数字"),
+		},
+	}
+	v := NewTagValidator()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Validate(tc.val)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tc.wantErr.Error(), err.Error())
+			if errs, ok := tc.wantErr.(ValidationErrors); ok {
+				assert.Equal(t, errs, err)
+			}
+		})
+	}
+}
+
+func TestTagValidator_RegisterRule(t *testing.T) {
+	v := NewTagValidator(TagValidatorWithTagName("check"))
+	v.RegisterRule("prefix", func(val reflect.Value, param string) bool {
+		return strings.HasPrefix(val.String(), param)
+	})
+	type Order struct {
+		SN string `check:"prefix=SN"`
+	}
+	assert.NoError(t, v.Validate(Order{SN: "SN123"}))
+	assert.Equal(t, ValidationErrors{
+		{Field: "SN", Rule: "prefix", Param: "SN", Message: "SN 没有通过 prefix 校验"},
+	}, v.Validate(&Order{SN: "123"}))
+}
@@ -0,0 +1,220 @@
+package web
+
+import (
+	"encoding"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"leanring-go/micro/rpc/serialize/json"
+	"leanring-go/micro/rpc/serialize/proto"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotAcceptable 没有一个编解码器能够满足 Accept
+	ErrNotAcceptable = errors.New("web: 没有满足 Accept 的编解码器")
+	// ErrUnsupportedMediaType 没有和 Content-Type 对应的编解码器
+	ErrUnsupportedMediaType = errors.New("web: 不支持的 Content-Type")
+)
+
+// Codec 编解码器
+// micro/rpc/serialize 里面的 Serializer 都实现了 Codec，可以直接注册，
+// 这样 HTTP 和 RPC 共用同一套编解码的实现
+type Codec interface {
+	Encode(val any) ([]byte, error)
+	Decode(data []byte, val any) error
+}
+
+// CodecRegistry 媒体类型到编解码器的映射
+// Context.Negotiate 根据 Accept 从这里选择编码器，Context.Bind 根据 Content-Type 选择解码器
+// 注册完之后就不要再修改了，CodecRegistry 不是并发安全的
+type CodecRegistry struct {
+	codecs map[string]Codec
+	// 按照注册的顺序排列，Accept 是 */* 或者 text/* 这种的时候，选择最先注册的
+	mediaTypes []string
+}
+
+// NewCodecRegistry 创建 CodecRegistry，默认注册了
+// application/json、application/xml、text/xml、text/plain、
+// application/x-protobuf 和 application/protobuf
+// 没有 Accept 的时候使用 application/json
+func NewCodecRegistry() *CodecRegistry {
+	res := &CodecRegistry{
+		codecs: make(map[string]Codec, 8),
+	}
+	res.Register("application/json", &json.Serializer{})
+	res.Register("application/xml", xmlCodec{})
+	res.Register("text/xml", xmlCodec{})
+	res.Register("text/plain", textCodec{})
+	res.Register("application/x-protobuf", &proto.Serializer{})
+	res.Register("application/protobuf", &proto.Serializer{})
+	return res
+}
+
+// Register 注册编解码器，mediaType 例如 application/json，不需要带参数
+// 重复注册会覆盖之前的编解码器，但是不改变它的顺序
+func (r *CodecRegistry) Register(mediaType string, codec Codec) {
+	mediaType = strings.ToLower(mediaType)
+	if _, ok := r.codecs[mediaType]; !ok {
+		r.mediaTypes = append(r.mediaTypes, mediaType)
+	}
+	r.codecs[mediaType] = codec
+}
+
+// Get 根据 Content-Type 获取编解码器，contentType 可以带参数，例如 charset
+func (r *CodecRegistry) Get(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codec, ok := r.codecs[mediaType]
+	return codec, ok
+}
+
+// negotiate 根据 Accept 选择编码器，返回响应的媒体类型
+// 每个媒体类型的 q 值来自于匹配它的最具体的一项，q=0 代表客户端拒绝这个类型
+// 选择 q 值最大的，q 值相同的时候匹配的项更具体的优先，再相同的话选择先注册的
+// 没有 Accept 的时候选择最先注册的
+func (r *CodecRegistry) negotiate(accept string) (string, Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		if len(r.mediaTypes) == 0 {
+			return "", nil, false
+		}
+		return r.mediaTypes[0], r.codecs[r.mediaTypes[0]], true
+	}
+	ranges := parseAccept(accept)
+	best, bestQ, bestSpecificity := "", 0.0, -1
+	for _, mediaType := range r.mediaTypes {
+		for _, rng := range ranges {
+			if !rng.match(mediaType) {
+				continue
+			}
+			// ranges 按照具体程度排好序了，第一个匹配的就是最具体的
+			if rng.q > bestQ || (rng.q == bestQ && rng.q > 0 && rng.specificity() > bestSpecificity) {
+				best, bestQ, bestSpecificity = mediaType, rng.q, rng.specificity()
+			}
+			break
+		}
+	}
+	if best == "" {
+		return "", nil, false
+	}
+	return best, r.codecs[best], true
+}
+
+// mediaRange Accept 里面的一项，例如 text/*;q=0.8
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+func (m mediaRange) match(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	return (m.typ == "*" || m.typ == typ) && (m.subtype == "*" || m.subtype == subtype)
+}
+
+// specificity type/subtype 比 type/* 更具体，type/* 比 */* 更具体
+func (m mediaRange) specificity() int {
+	switch {
+	case m.typ == "*":
+		return 0
+	case m.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseAccept 解析 Accept，忽略格式错误的项，结果按照具体程度从高到低排列
+// q=0 的项需要保留，它代表客户端拒绝匹配的类型
+func parseAccept(accept string) []mediaRange {
+	var res []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			// 有些客户端会发送 * 表示 */*
+			if mediaType != "*" {
+				continue
+			}
+			typ, subtype = "*", "*"
+		}
+		q := 1.0
+		if val, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if q < 0 || q > 1 {
+			continue
+		}
+		res = append(res, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].specificity() > res[j].specificity()
+	})
+	return res
+}
+
+// ServerWithCodecs 设置编解码器，一般是在 NewCodecRegistry 的基础上注册自己的编解码器
+func ServerWithCodecs(codecs *CodecRegistry) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.codecs = codecs
+	}
+}
+
+// xmlCodec 基于 encoding/xml 的编解码器
+type xmlCodec struct{}
+
+func (xmlCodec) Encode(val any) ([]byte, error) {
+	return xml.Marshal(val)
+}
+
+func (xmlCodec) Decode(data []byte, val any) error {
+	return xml.Unmarshal(data, val)
+}
+
+// textCodec 纯文本的编解码器
+// 编码的时候依次尝试 string、[]byte、encoding.TextMarshaler、fmt.Stringer 和 error，
+// 都不是的话使用 fmt.Sprint
+type textCodec struct{}
+
+func (textCodec) Encode(val any) ([]byte, error) {
+	switch v := val.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case encoding.TextMarshaler:
+		return v.MarshalText()
+	case fmt.Stringer:
+		return []byte(v.String()), nil
+	case error:
+		return []byte(v.Error()), nil
+	default:
+		return []byte(fmt.Sprint(val)), nil
+	}
+}
+
+// Decode 只能解码到 *string、*[]byte 或者 encoding.TextUnmarshaler
+func (textCodec) Decode(data []byte, val any) error {
+	switch v := val.(type) {
+	case *string:
+		*v = string(data)
+	case *[]byte:
+		*v = append((*v)[:0], data...)
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(data)
+	default:
+		return fmt.Errorf("web: text/plain 不能解码到 %T", val)
+	}
+	return nil
+}
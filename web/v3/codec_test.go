@@ -0,0 +1,278 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"leanring-go/micro/proto/gen"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type negotiateUser struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (u negotiateUser) String() string {
+	return "user " + u.Name
+}
+
+func TestContext_Negotiate(t *testing.T) {
+	pbData, err := proto.Marshal(&gen.User{Id: 1, Name: "Tom"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name            string
+		accept          string
+		val             any
+		wantCode        int
+		wantContentType string
+		wantBody        string
+		wantErr         error
+	}{
+		{
+			name:            "no accept",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"Tom"}`,
+		},
+		{
+			name:            "xml",
+			accept:          "application/xml",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<negotiateUser><name>Tom</name></negotiateUser>`,
+		},
+		{
+			name:            "text",
+			accept:          "text/plain",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "user Tom",
+		},
+		{
+			name:            "protobuf",
+			accept:          "application/x-protobuf",
+			val:             &gen.User{Id: 1, Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "application/x-protobuf",
+			wantBody:        string(pbData),
+		},
+		{
+			// q 值大的优先
+			name:            "quality",
+			accept:          "application/json;q=0.5, application/xml;q=0.9",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<negotiateUser><name>Tom</name></negotiateUser>`,
+		},
+		{
+			// q 值相同的时候更具体的优先
+			name:            "specificity",
+			accept:          "*/*, text/plain",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "user Tom",
+		},
+		{
+			name:            "wildcard subtype",
+			accept:          "text/*",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "text/xml; charset=utf-8",
+			wantBody:        `<negotiateUser><name>Tom</name></negotiateUser>`,
+		},
+		{
+			// 不支持的类型和 q=0 的类型都跳过
+			name:            "skip unsupported",
+			accept:          "image/png, application/xml;q=0, application/json;q=0.1",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"Tom"}`,
+		},
+		{
+			// q=0 代表拒绝，即便 */* 能够匹配
+			name:            "refuse with q=0",
+			accept:          "application/json;q=0, */*",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<negotiateUser><name>Tom</name></negotiateUser>`,
+		},
+		{
+			// 更具体的项覆盖通配的项
+			name:            "specific overrides refused wildcard",
+			accept:          "text/*;q=0, text/plain",
+			val:             negotiateUser{Name: "Tom"},
+			wantCode:        http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "user Tom",
+		},
+		{
+			name:     "refuse all",
+			accept:   "*/*;q=0",
+			val:      negotiateUser{Name: "Tom"},
+			wantCode: http.StatusNotAcceptable,
+			wantBody: "NOT ACCEPTABLE",
+			wantErr:  ErrNotAcceptable,
+		},
+		{
+			name:     "not acceptable",
+			accept:   "image/png",
+			val:      negotiateUser{Name: "Tom"},
+			wantCode: http.StatusNotAcceptable,
+			wantBody: "NOT ACCEPTABLE",
+			wantErr:  ErrNotAcceptable,
+		},
+		{
+			// 编码失败不能返回 200 和空的响应体
+			name:     "encode error",
+			accept:   "application/x-protobuf",
+			val:      negotiateUser{Name: "Tom"},
+			wantCode: http.StatusInternalServerError,
+			wantBody: "INTERNAL SERVER ERROR",
+			wantErr:  errors.New("micro: 必须是 proto.Message"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			recorder := httptest.NewRecorder()
+			ctx := &Context{Req: req, Resp: recorder}
+			err := ctx.Negotiate(http.StatusOK, tc.val)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCode, ctx.RespStatusCode)
+			assert.Equal(t, tc.wantBody, string(ctx.RespData))
+			assert.Equal(t, tc.wantContentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestContext_Bind(t *testing.T) {
+	pbData, err := proto.Marshal(&gen.User{Id: 1, Name: "Tom"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		val         any
+		wantVal     any
+		wantErr     error
+	}{
+		{
+			// 没有 Content-Type 当作 JSON
+			name:    "no content type",
+			body:    `{"name":"Tom"}`,
+			val:     &negotiateUser{},
+			wantVal: &negotiateUser{Name: "Tom"},
+		},
+		{
+			name:        "json with charset",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"Tom"}`,
+			val:         &negotiateUser{},
+			wantVal:     &negotiateUser{Name: "Tom"},
+		},
+		{
+			name:        "xml",
+			contentType: "text/xml",
+			body:        `<negotiateUser><name>Tom</name></negotiateUser>`,
+			val:         &negotiateUser{},
+			wantVal:     &negotiateUser{Name: "Tom"},
+		},
+		{
+			name:        "text",
+			contentType: "text/plain",
+			body:        "hello",
+			val:         new(string),
+			wantVal: func() *string {
+				s := "hello"
+				return &s
+			}(),
+		},
+		{
+			name:        "text unsupported type",
+			contentType: "text/plain",
+			body:        "hello",
+			val:         &negotiateUser{},
+			wantErr:     errors.New("web: text/plain 不能解码到 *web.negotiateUser"),
+		},
+		{
+			name:        "unsupported media type",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=Tom",
+			val:         &negotiateUser{},
+			wantErr:     ErrUnsupportedMediaType,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			ctx := &Context{Req: req}
+			err := ctx.Bind(tc.val)
+			if tc.wantErr == ErrUnsupportedMediaType {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, tc.val)
+		})
+	}
+
+	// protobuf 的消息不能直接用 assert.Equal 比较
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(pbData)))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	ctx := &Context{Req: req}
+	user := &gen.User{}
+	require.NoError(t, ctx.Bind(user))
+	assert.Equal(t, int64(1), user.Id)
+	assert.Equal(t, "Tom", user.Name)
+}
+
+func TestHTTPServer_Codecs(t *testing.T) {
+	codecs := NewCodecRegistry()
+	// 覆盖默认的 JSON 编解码器
+	codecs.Register("application/json", textCodec{})
+	h := NewHTTPServer(ServerWithCodecs(codecs))
+	h.Get("/user", func(ctx *Context) {
+		_ = ctx.Negotiate(http.StatusOK, negotiateUser{Name: "Tom"})
+	})
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user", nil))
+	assert.Equal(t, "user Tom", recorder.Body.String())
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+}
+
+func TestHTTPServer_NegotiateEncodeError(t *testing.T) {
+	h := NewHTTPServer()
+	h.Get("/user", func(ctx *Context) {
+		// 忽略了 error 也不会返回 200 和空的响应体
+		_ = ctx.Negotiate(http.StatusOK, negotiateUser{Name: "Tom"})
+	})
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "INTERNAL SERVER ERROR", recorder.Body.String())
+}
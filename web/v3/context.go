@@ -1,9 +1,361 @@
 package web
 
-import "net/http"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"leanring-go/validate"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
+// Context 由 HTTPServer 从池里面取出来复用，
+// 所以 handler 返回之后不能再持有 Context
 type Context struct {
 	Req        *http.Request
 	Resp       http.ResponseWriter
-	PathParams map[string]string
+	PathParams Params
+	// MatchedRoute 命中的路由，例如 /order/detail/:id
+	// 没有命中的时候为空字符串
+	MatchedRoute string
+
+	// 响应由框架在最后统一写回，
+	// 这样 Middleware 在 handler 执行之后还能读取或者修改响应
+	RespStatusCode int
+	RespData       []byte
+
+	// UserValues 在同一个请求的 Middleware 和 handler 之间传递数据
+	// 例如缓存 session
+	UserValues map[string]any
+
+	// 缓存查询参数，URL.Query() 每次调用都会重新解析
+	queryValues url.Values
+
+	// 由 HTTPServer 传进来
+	tplEngine TemplateEngine
+	// 由 HTTPServer 传进来，为 nil 的时候使用 defaultCodecs
+	codecs *CodecRegistry
+	// 由 HTTPServer 传进来，为 nil 的时候使用 defaultValidator
+	validator validate.Validator
+}
+
+// defaultCodecs 没有设置 CodecRegistry 的时候使用
+var defaultCodecs = NewCodecRegistry()
+
+// reset 重置 Context，以便处理新的请求
+// PathParams 保留底层数组，避免每次请求都分配内存
+func (c *Context) reset(req *http.Request, resp http.ResponseWriter, server *HTTPServer) {
+	*c = Context{
+		Req:        req,
+		Resp:       resp,
+		PathParams: c.PathParams[:0],
+		tplEngine:  server.tplEngine,
+		codecs:     server.codecs,
+		validator:  server.validator,
+	}
+}
+
+// Param 路径参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 路径参数，按照在路由里面出现的顺序排列
+type Params []Param
+
+// Get 获取路径参数
+func (ps Params) Get(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// BindJSON 把请求体当作 JSON 解析到 val 里面，然后校验 val
+// val 必须是指针
+// 校验不通过的时候返回 validate.ValidationErrors，并且已经设置好了 400 响应
+func (c *Context) BindJSON(val any) error {
+	if val == nil {
+		return errors.New("web: 输入不能为 nil")
+	}
+	if c.Req.Body == nil {
+		return errors.New("web: body 为 nil")
+	}
+	decoder := json.NewDecoder(c.Req.Body)
+	if err := decoder.Decode(val); err != nil {
+		return err
+	}
+	return c.validate(val)
+}
+
+// Bind 根据 Content-Type 选择解码器，把请求体解析到 val 里面，然后校验 val
+// 没有 Content-Type 的时候当作 JSON 处理
+// 没有对应的解码器的时候返回 ErrUnsupportedMediaType
+// 校验不通过的时候和 BindJSON 一样
+func (c *Context) Bind(val any) error {
+	if val == nil {
+		return errors.New("web: 输入不能为 nil")
+	}
+	if c.Req.Body == nil {
+		return errors.New("web: body 为 nil")
+	}
+	contentType := c.Req.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	codec, ok := c.codecRegistry().Get(contentType)
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnsupportedMediaType, contentType)
+	}
+	data, err := io.ReadAll(c.Req.Body)
+	if err != nil {
+		return err
+	}
+	if err = codec.Decode(data, val); err != nil {
+		return err
+	}
+	return c.validate(val)
+}
+
+// FormValue 获取表单参数，包括查询参数和 body 里面的表单
+func (c *Context) FormValue(key string) StringValue {
+	// ParseForm 多次调用是安全的，解析过就不会重复解析
+	if err := c.Req.ParseForm(); err != nil {
+		return StringValue{err: err}
+	}
+	vals, ok := c.Req.Form[key]
+	if !ok || len(vals) == 0 {
+		return StringValue{err: fmt.Errorf("web: 找不到表单参数 %s", key)}
+	}
+	return StringValue{val: vals[0]}
+}
+
+// QueryValue 获取查询参数
+func (c *Context) QueryValue(key string) StringValue {
+	if c.queryValues == nil {
+		c.queryValues = c.Req.URL.Query()
+	}
+	vals, ok := c.queryValues[key]
+	if !ok || len(vals) == 0 {
+		return StringValue{err: fmt.Errorf("web: 找不到查询参数 %s", key)}
+	}
+	return StringValue{val: vals[0]}
+}
+
+// BindQuery 把查询参数解析到结构体指针 val 里面，然后校验 val
+// 字段名来自 query 标签，没有标签的使用字段名，query:"-" 的字段会被忽略
+// 支持字符串、布尔值、数字以及它们的切片，切片对应同名的多个查询参数
+// 校验不通过的时候和 BindJSON 一样
+func (c *Context) BindQuery(val any) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("web: 输入必须是结构体指针")
+	}
+	if c.queryValues == nil {
+		c.queryValues = c.Req.URL.Query()
+	}
+	rv = rv.Elem()
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+		if !fd.IsExported() {
+			continue
+		}
+		name := fd.Tag.Get("query")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = fd.Name
+		}
+		vals, ok := c.queryValues[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if err := setQueryField(rv.Field(i), vals); err != nil {
+			return fmt.Errorf("web: 查询参数 %s 格式错误 %w", name, err)
+		}
+	}
+	return c.validate(val)
+}
+
+func setQueryField(fv reflect.Value, vals []string) error {
+	if fv.Kind() == reflect.Slice {
+		res := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setQueryValue(res.Index(i), val); err != nil {
+				return err
+			}
+		}
+		fv.Set(res)
+		return nil
+	}
+	return setQueryValue(fv, vals[0])
+}
+
+func setQueryValue(fv reflect.Value, val string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Pointer:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setQueryValue(elem.Elem(), val); err != nil {
+			return err
+		}
+		fv.Set(elem)
+	default:
+		return fmt.Errorf("不支持的类型 %s", fv.Type())
+	}
+	return nil
+}
+
+// PathValue 获取路径参数
+func (c *Context) PathValue(key string) StringValue {
+	val, ok := c.PathParams.Get(key)
+	if !ok {
+		return StringValue{err: fmt.Errorf("web: 找不到路径参数 %s", key)}
+	}
+	return StringValue{val: val}
+}
+
+// RespJSON 以 JSON 格式返回响应
+func (c *Context) RespJSON(code int, val any) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	c.Resp.Header().Set("Content-Type", "application/json")
+	c.RespStatusCode = code
+	c.RespData = data
+	return nil
+}
+
+// Negotiate 根据 Accept 选择编码器返回响应，支持 JSON、XML、纯文本和 protobuf
+// 没有 Accept 的时候使用 JSON
+// 没有满足 Accept 的编码器的时候返回 406 和 ErrNotAcceptable
+// 编码失败的时候返回 500，例如 protobuf 只能编码 proto.Message
+func (c *Context) Negotiate(code int, val any) error {
+	// 响应随着 Accept 变化，缓存需要区分
+	c.Resp.Header().Add("Vary", "Accept")
+	mediaType, codec, ok := c.codecRegistry().negotiate(c.Req.Header.Get("Accept"))
+	if !ok {
+		c.RespStatusCode = http.StatusNotAcceptable
+		c.RespData = []byte("NOT ACCEPTABLE")
+		return ErrNotAcceptable
+	}
+	data, err := codec.Encode(val)
+	if err != nil {
+		c.RespStatusCode = http.StatusInternalServerError
+		c.RespData = []byte("INTERNAL SERVER ERROR")
+		return err
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		mediaType += "; charset=utf-8"
+	}
+	c.Resp.Header().Set("Content-Type", mediaType)
+	c.RespStatusCode = code
+	c.RespData = data
+	return nil
+}
+
+func (c *Context) codecRegistry() *CodecRegistry {
+	if c.codecs == nil {
+		return defaultCodecs
+	}
+	return c.codecs
+}
+
+// RespString 以纯文本格式返回响应
+func (c *Context) RespString(code int, val string) {
+	c.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.RespStatusCode = code
+	c.RespData = []byte(val)
+}
+
+// Render 使用模板引擎渲染页面
+// 渲染失败的时候返回 500，不会写回渲染了一半的页面
+func (c *Context) Render(tplName string, data any) error {
+	if c.tplEngine == nil {
+		c.RespStatusCode = http.StatusInternalServerError
+		c.RespData = []byte("INTERNAL SERVER ERROR")
+		return errors.New("web: 没有设置模板引擎")
+	}
+	page, err := c.tplEngine.Render(c.Req.Context(), tplName, data)
+	if err != nil {
+		c.RespStatusCode = http.StatusInternalServerError
+		c.RespData = []byte("INTERNAL SERVER ERROR")
+		return err
+	}
+	c.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.RespStatusCode = http.StatusOK
+	c.RespData = page
+	return nil
+}
+
+// Redirect 重定向到 location
+// code 必须是 3xx 的重定向状态码
+func (c *Context) Redirect(code int, location string) error {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		return fmt.Errorf("web: 非法的重定向状态码 %d", code)
+	}
+	c.Resp.Header().Set("Location", location)
+	c.RespStatusCode = code
+	c.RespData = nil
+	return nil
+}
+
+// StringValue 从请求里面取出来的字符串值
+// 取值失败的时候 err 不为 nil，之后所有的转换都会返回这个 err
+type StringValue struct {
+	val string
+	err error
+}
+
+func (s StringValue) AsString() (string, error) {
+	return s.val, s.err
+}
+
+func (s StringValue) AsInt64() (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return strconv.ParseInt(s.val, 10, 64)
+}
+
+func (s StringValue) AsBool() (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	return strconv.ParseBool(s.val)
 }
@@ -0,0 +1,158 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestContext_BindJSON(t *testing.T) {
+	type User struct {
+		Name string `json:"name"`
+	}
+	testCases := []struct {
+		name     string
+		req      *http.Request
+		val      any
+		wantVal  any
+		wantErr  error
+		wantFail bool
+	}{
+		{
+			name:    "nil val",
+			req:     httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"Tom"}`)),
+			wantErr: errors.New("web: 输入不能为 nil"),
+		},
+		{
+			name: "nil body",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/", nil)
+				req.Body = nil
+				return req
+			}(),
+			val:     &User{},
+			wantErr: errors.New("web: body 为 nil"),
+		},
+		{
+			name:     "invalid json",
+			req:      httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":`)),
+			val:      &User{},
+			wantFail: true,
+		},
+		{
+			name:    "user",
+			req:     httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"Tom"}`)),
+			val:     &User{},
+			wantVal: &User{Name: "Tom"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &Context{Req: tc.req}
+			err := ctx.BindJSON(tc.val)
+			if tc.wantFail {
+				assert.Error(t, err)
+				return
+			}
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantVal, tc.val)
+		})
+	}
+}
+
+func TestContext_FormValue(t *testing.T) {
+	form := url.Values{}
+	form.Set("age", "18")
+	req := httptest.NewRequest(http.MethodPost, "/?admin=true", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := &Context{Req: req}
+
+	age, err := ctx.FormValue("age").AsInt64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(18), age)
+
+	// 查询参数也在表单里面
+	admin, err := ctx.FormValue("admin").AsBool()
+	assert.NoError(t, err)
+	assert.True(t, admin)
+
+	_, err = ctx.FormValue("name").AsString()
+	assert.Equal(t, errors.New("web: 找不到表单参数 name"), err)
+}
+
+func TestContext_QueryValue(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?id=12&name=Tom&admin=abc", nil)
+	ctx := &Context{Req: req}
+
+	id, err := ctx.QueryValue("id").AsInt64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), id)
+	// 第一次调用之后就缓存了
+	assert.NotNil(t, ctx.queryValues)
+
+	name, err := ctx.QueryValue("name").AsString()
+	assert.NoError(t, err)
+	assert.Equal(t, "Tom", name)
+
+	_, err = ctx.QueryValue("admin").AsBool()
+	assert.IsType(t, &strconv.NumError{}, err)
+
+	_, err = ctx.QueryValue("age").AsInt64()
+	assert.Equal(t, errors.New("web: 找不到查询参数 age"), err)
+}
+
+func TestContext_PathValue(t *testing.T) {
+	ctx := &Context{PathParams: Params{{Key: "id", Value: "123"}, {Key: "name", Value: "Tom"}}}
+
+	id, err := ctx.PathValue("id").AsInt64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123), id)
+
+	_, err = ctx.PathValue("name").AsInt64()
+	assert.IsType(t, &strconv.NumError{}, err)
+
+	_, err = ctx.PathValue("age").AsString()
+	assert.Equal(t, errors.New("web: 找不到路径参数 age"), err)
+}
+
+func TestContext_RespJSON(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx := &Context{Resp: recorder}
+	err := ctx.RespJSON(http.StatusOK, map[string]string{"name": "Tom"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, ctx.RespStatusCode)
+	assert.Equal(t, `{"name":"Tom"}`, string(ctx.RespData))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	err = ctx.RespJSON(http.StatusOK, make(chan int))
+	assert.Error(t, err)
+}
+
+func TestContext_RespString(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx := &Context{Resp: recorder}
+	ctx.RespString(http.StatusBadRequest, "bad request")
+	assert.Equal(t, http.StatusBadRequest, ctx.RespStatusCode)
+	assert.Equal(t, "bad request", string(ctx.RespData))
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+}
+
+func TestContext_Redirect(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx := &Context{Resp: recorder}
+	err := ctx.Redirect(http.StatusOK, "/login")
+	assert.Equal(t, errors.New("web: 非法的重定向状态码 200"), err)
+
+	err = ctx.Redirect(http.StatusFound, "/login")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, ctx.RespStatusCode)
+	assert.Equal(t, "/login", recorder.Header().Get("Location"))
+}
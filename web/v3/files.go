@@ -0,0 +1,388 @@
+package web
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// errFileTooLarge 上传的文件超过了 FileUploader.MaxSize
+var errFileTooLarge = errors.New("web: 文件太大")
+
+// FileStorage 保存上传的文件
+type FileStorage interface {
+	// Save 把 src 的内容保存到 dst
+	// 读取 src 出错的时候，需要清理掉已经写入的部分
+	Save(ctx context.Context, dst string, src io.Reader) error
+}
+
+// LocalFileStorage 保存到本地磁盘
+type LocalFileStorage struct{}
+
+func (l LocalFileStorage) Save(ctx context.Context, dst string, src io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, src)
+	if er := file.Close(); err == nil {
+		err = er
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+	return err
+}
+
+// FileUploader 文件上传
+// 直接读取 multipart 的流，文件内容不会全部缓存在内存里面
+type FileUploader struct {
+	// FileField 文件在表单里面的字段名
+	FileField string
+	// DstPathFunc 计算目标路径，例如根据 part.FileName() 生成
+	DstPathFunc func(part *multipart.Part) string
+	// MaxSize 文件的最大字节数，小于等于 0 表示不限制
+	MaxSize int64
+	// Storage 为 nil 的时候保存到本地磁盘
+	Storage FileStorage
+}
+
+func (u *FileUploader) Handle() HandleFunc {
+	storage := u.Storage
+	if storage == nil {
+		storage = LocalFileStorage{}
+	}
+	return func(ctx *Context) {
+		if u.MaxSize > 0 {
+			// 限制整个请求体，多留 1M 给其它的表单字段
+			ctx.Req.Body = http.MaxBytesReader(ctx.Resp, ctx.Req.Body, u.MaxSize+1<<20)
+		}
+		reader, err := ctx.Req.MultipartReader()
+		if err != nil {
+			ctx.RespStatusCode = http.StatusBadRequest
+			ctx.RespData = []byte("请求不是 multipart 表单")
+			return
+		}
+		part, err := u.filePart(reader)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.RespStatusCode = http.StatusRequestEntityTooLarge
+			ctx.RespData = []byte("文件太大")
+			return
+		}
+		if err != nil {
+			ctx.RespStatusCode = http.StatusBadRequest
+			ctx.RespData = []byte("找不到上传的文件")
+			return
+		}
+		defer part.Close()
+
+		var src io.Reader = part
+		if u.MaxSize > 0 {
+			src = &maxSizeReader{r: part, remaining: u.MaxSize}
+		}
+		err = storage.Save(ctx.Req.Context(), u.DstPathFunc(part), src)
+		switch {
+		case err == nil:
+			ctx.RespStatusCode = http.StatusOK
+			ctx.RespData = []byte("上传成功")
+		case errors.Is(err, errFileTooLarge), errors.As(err, &maxBytesErr):
+			ctx.RespStatusCode = http.StatusRequestEntityTooLarge
+			ctx.RespData = []byte("文件太大")
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			// multipart 的格式有问题
+			ctx.RespStatusCode = http.StatusBadRequest
+			ctx.RespData = []byte("上传失败")
+		default:
+			ctx.RespStatusCode = http.StatusInternalServerError
+			ctx.RespData = []byte("上传失败")
+		}
+	}
+}
+
+// filePart 找到 FileField 对应的文件
+func (u *FileUploader) filePart(reader *multipart.Reader) (*multipart.Part, error) {
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == u.FileField && part.FileName() != "" {
+			return part, nil
+		}
+		_ = part.Close()
+	}
+}
+
+// maxSizeReader 读到的数据超过 remaining 就返回 errFileTooLarge
+type maxSizeReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	if m.remaining < 0 {
+		return 0, errFileTooLarge
+	}
+	// 多读一个字节，用来判断是否超过了限制
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+// StaticResourceHandler 静态资源处理
+// 需要注册在通配符路由上，例如：
+// h.Get("/static/*filepath", handler.Handle)
+// 小文件会缓存在内存里面，文件内容变化之后需要重启才能生效
+type StaticResourceHandler struct {
+	fsys fs.FS
+	// 扩展名到 Content-Type 的映射，优先于 mime.TypeByExtension
+	extContentTypeMap map[string]string
+
+	// 不超过 maxFileSize 的文件才会被缓存
+	maxFileSize int64
+	cache       *fileCache
+}
+
+type StaticResourceHandlerOption func(h *StaticResourceHandler)
+
+// NewStaticResourceHandler 从目录 dir 里面读取静态资源
+func NewStaticResourceHandler(dir string, opts ...StaticResourceHandlerOption) *StaticResourceHandler {
+	return NewStaticResourceHandlerFS(os.DirFS(dir), opts...)
+}
+
+// NewStaticResourceHandlerFS 从 fsys 里面读取静态资源，一般配合 embed.FS 使用
+func NewStaticResourceHandlerFS(fsys fs.FS, opts ...StaticResourceHandlerOption) *StaticResourceHandler {
+	res := &StaticResourceHandler{
+		fsys: fsys,
+		extContentTypeMap: map[string]string{
+			".jpeg": "image/jpeg",
+			".jpe":  "image/jpeg",
+			".jpg":  "image/jpeg",
+			".png":  "image/png",
+			".pdf":  "application/pdf",
+		},
+		// 默认缓存 1M 以下的文件，总共不超过 100M
+		maxFileSize: 1 << 20,
+		cache:       newFileCache(100 << 20),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// StaticWithMaxFileSize 设置可以缓存的单个文件的最大字节数
+func StaticWithMaxFileSize(maxSize int64) StaticResourceHandlerOption {
+	return func(h *StaticResourceHandler) {
+		h.maxFileSize = maxSize
+	}
+}
+
+// StaticWithCacheSize 设置缓存的总字节数，为 0 表示不缓存
+func StaticWithCacheSize(capacity int64) StaticResourceHandlerOption {
+	return func(h *StaticResourceHandler) {
+		h.cache = newFileCache(capacity)
+	}
+}
+
+// StaticWithExtension 追加或者覆盖扩展名到 Content-Type 的映射
+func StaticWithExtension(extMap map[string]string) StaticResourceHandlerOption {
+	return func(h *StaticResourceHandler) {
+		for ext, contentType := range extMap {
+			h.extContentTypeMap[ext] = contentType
+		}
+	}
+}
+
+func (s *StaticResourceHandler) Handle(ctx *Context) {
+	name, err := ctx.PathValue("filepath").AsString()
+	if err != nil {
+		name, err = ctx.PathValue("*").AsString()
+	}
+	// fs.ValidPath 不允许出现 .. 和 . 这种路径，防止路径穿越
+	if err != nil || !fs.ValidPath(name) || name == "." {
+		ctx.RespStatusCode = http.StatusBadRequest
+		ctx.RespData = []byte("BAD REQUEST")
+		return
+	}
+	contentType := s.contentType(name)
+
+	if data, ok := s.cache.get(name); ok {
+		s.writeData(ctx, contentType, data)
+		return
+	}
+
+	file, err := s.fsys.Open(name)
+	if err != nil {
+		ctx.RespStatusCode = http.StatusNotFound
+		ctx.RespData = []byte("NOT FOUND")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	// 不支持列出目录
+	if err != nil || info.IsDir() {
+		ctx.RespStatusCode = http.StatusNotFound
+		ctx.RespData = []byte("NOT FOUND")
+		return
+	}
+
+	// 大文件直接写回，不经过内存
+	if info.Size() > s.maxFileSize {
+		ctx.Resp.Header().Set("Content-Type", contentType)
+		if seeker, ok := file.(io.ReadSeeker); ok {
+			http.ServeContent(ctx.Resp, ctx.Req, info.Name(), info.ModTime(), seeker)
+			return
+		}
+		_, _ = io.Copy(ctx.Resp, file)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.RespStatusCode = http.StatusInternalServerError
+		ctx.RespData = []byte("INTERNAL SERVER ERROR")
+		return
+	}
+	s.cache.put(name, data)
+	s.writeData(ctx, contentType, data)
+}
+
+func (s *StaticResourceHandler) contentType(name string) string {
+	ext := path.Ext(name)
+	if contentType, ok := s.extContentTypeMap[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+func (s *StaticResourceHandler) writeData(ctx *Context, contentType string, data []byte) {
+	ctx.Resp.Header().Set("Content-Type", contentType)
+	ctx.RespStatusCode = http.StatusOK
+	ctx.RespData = data
+}
+
+// fileCache 按照总字节数限制大小的 LRU 缓存
+type fileCache struct {
+	mutex    sync.Mutex
+	capacity int64
+	size     int64
+	// 越靠前越是最近访问过的
+	entries *list.List
+	index   map[string]*list.Element
+}
+
+type fileCacheEntry struct {
+	name string
+	data []byte
+}
+
+func newFileCache(capacity int64) *fileCache {
+	return &fileCache{
+		capacity: capacity,
+		entries:  list.New(),
+		index:    make(map[string]*list.Element),
+	}
+}
+
+func (c *fileCache) get(name string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ele, ok := c.index[name]
+	if !ok {
+		return nil, false
+	}
+	c.entries.MoveToFront(ele)
+	return ele.Value.(*fileCacheEntry).data, true
+}
+
+func (c *fileCache) put(name string, data []byte) {
+	size := int64(len(data))
+	if size > c.capacity {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.index[name]; ok {
+		return
+	}
+	// 淘汰最久没有访问的文件，直到放得下
+	for c.size+size > c.capacity {
+		oldest := c.entries.Back()
+		entry := c.entries.Remove(oldest).(*fileCacheEntry)
+		delete(c.index, entry.name)
+		c.size -= int64(len(entry.data))
+	}
+	c.index[name] = c.entries.PushFront(&fileCacheEntry{name: name, data: data})
+	c.size += size
+}
+
+// FileDownloader 文件下载
+// 要下载的文件名通过查询参数 file 指定，只能下载 Dir 下面的文件
+type FileDownloader struct {
+	// Dir 可以是相对路径，为空的时候使用当前目录
+	Dir string
+}
+
+func (d *FileDownloader) Handle() HandleFunc {
+	return func(ctx *Context) {
+		req, err := ctx.QueryValue("file").AsString()
+		if err != nil || req == "" {
+			ctx.RespStatusCode = http.StatusBadRequest
+			ctx.RespData = []byte("找不到目标文件")
+			return
+		}
+		dir := d.Dir
+		if dir == "" {
+			dir = "."
+		}
+		// 先拼上 / 再 Clean，这样 .. 最多只能回到 Dir
+		dst := filepath.Join(dir, filepath.Clean("/"+req))
+		file, err := os.Open(dst)
+		if err != nil {
+			ctx.RespStatusCode = http.StatusNotFound
+			ctx.RespData = []byte("找不到目标文件")
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			ctx.RespStatusCode = http.StatusNotFound
+			ctx.RespData = []byte("找不到目标文件")
+			return
+		}
+
+		header := ctx.Resp.Header()
+		header.Set("Content-Disposition",
+			mime.FormatMediaType("attachment", map[string]string{"filename": info.Name()}))
+		header.Set("Content-Description", "File Transfer")
+		header.Set("Content-Type", "application/octet-stream")
+		header.Set("Content-Transfer-Encoding", "binary")
+		header.Set("Expires", "0")
+		header.Set("Cache-Control", "must-revalidate")
+		header.Set("Pragma", "public")
+		http.ServeContent(ctx.Resp, ctx.Req, info.Name(), info.ModTime(), file)
+	}
+}
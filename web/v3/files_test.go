@@ -0,0 +1,360 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticResourceHandler_Handle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/a.css":    &fstest.MapFile{Data: []byte("body {}")},
+		"img/logo.png": &fstest.MapFile{Data: []byte("png")},
+		"js/app.js":    &fstest.MapFile{Data: []byte(strings.Repeat("a", 32))},
+		"data.unknown": &fstest.MapFile{Data: []byte("unknown")},
+	}
+	handler := NewStaticResourceHandlerFS(fsys, StaticWithMaxFileSize(16))
+	h := NewHTTPServer()
+	h.Get("/static/*filepath", handler.Handle)
+
+	testCases := []struct {
+		name            string
+		path            string
+		wantCode        int
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "css",
+			path:            "/static/css/a.css",
+			wantCode:        http.StatusOK,
+			wantBody:        "body {}",
+			wantContentType: "text/css; charset=utf-8",
+		},
+		{
+			name:            "png",
+			path:            "/static/img/logo.png",
+			wantCode:        http.StatusOK,
+			wantBody:        "png",
+			wantContentType: "image/png",
+		},
+		{
+			name:            "unknown extension",
+			path:            "/static/data.unknown",
+			wantCode:        http.StatusOK,
+			wantBody:        "unknown",
+			wantContentType: "application/octet-stream",
+		},
+		{
+			// 大文件不缓存，直接写回
+			name:            "large file",
+			path:            "/static/js/app.js",
+			wantCode:        http.StatusOK,
+			wantBody:        strings.Repeat("a", 32),
+			wantContentType: "text/javascript; charset=utf-8",
+		},
+		{
+			name:     "not found",
+			path:     "/static/css/b.css",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+		{
+			name:     "directory",
+			path:     "/static/css",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+		{
+			name:     "path traversal",
+			path:     "/static/css/../../secret",
+			wantCode: http.StatusBadRequest,
+			wantBody: "BAD REQUEST",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			// httptest 会清理路径，这里直接设置
+			req.URL.Path = tc.path
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			if tc.wantContentType != "" {
+				assert.Equal(t, tc.wantContentType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	_, ok := handler.cache.get("css/a.css")
+	assert.True(t, ok)
+	_, ok = handler.cache.get("js/app.js")
+	assert.False(t, ok)
+}
+
+func TestFileCache(t *testing.T) {
+	c := newFileCache(10)
+	c.put("a", []byte("aaaa"))
+	c.put("b", []byte("bbbb"))
+	// 访问 a，b 就成了最久没有访问的
+	_, ok := c.get("a")
+	assert.True(t, ok)
+	c.put("c", []byte("cccc"))
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	_, ok = c.get("a")
+	assert.True(t, ok)
+	_, ok = c.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, int64(8), c.size)
+
+	// 超过容量的文件不缓存
+	c.put("d", []byte("ddddddddddd"))
+	_, ok = c.get("d")
+	assert.False(t, ok)
+}
+
+func TestFileDownloader_Handle(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.csv"), []byte("id,name"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	h := NewHTTPServer()
+	h.Get("/download", (&FileDownloader{Dir: dir}).Handle())
+
+	testCases := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantBody  string
+		wantDispo string
+	}{
+		{
+			name:      "download",
+			query:     "file=report.csv",
+			wantCode:  http.StatusOK,
+			wantBody:  "id,name",
+			wantDispo: `attachment; filename=report.csv`,
+		},
+		{
+			// .. 最多只能回到 Dir
+			name:      "path traversal",
+			query:     "file=../../report.csv",
+			wantCode:  http.StatusOK,
+			wantBody:  "id,name",
+			wantDispo: `attachment; filename=report.csv`,
+		},
+		{
+			name:     "no file",
+			wantCode: http.StatusBadRequest,
+			wantBody: "找不到目标文件",
+		},
+		{
+			name:     "not found",
+			query:    "file=../secret",
+			wantCode: http.StatusNotFound,
+			wantBody: "找不到目标文件",
+		},
+		{
+			name:     "directory",
+			query:    "file=sub",
+			wantCode: http.StatusNotFound,
+			wantBody: "找不到目标文件",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/download?"+tc.query, nil)
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantDispo, recorder.Header().Get("Content-Disposition"))
+		})
+	}
+}
+
+func TestFileDownloader_HandleRelativeDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+
+	for _, d := range []string{".", "./", ""} {
+		t.Run("dir "+d, func(t *testing.T) {
+			h := NewHTTPServer()
+			h.Get("/download", (&FileDownloader{Dir: d}).Handle())
+			for _, query := range []string{"file=a.txt", "file=../a.txt", "file=/a.txt"} {
+				recorder := httptest.NewRecorder()
+				h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/download?"+query, nil))
+				assert.Equal(t, http.StatusOK, recorder.Code, query)
+				assert.Equal(t, "hello", recorder.Body.String(), query)
+			}
+		})
+	}
+}
+
+func TestFileUploader_Handle(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHTTPServer()
+	h.POST("/upload", (&FileUploader{
+		FileField: "myfile",
+		DstPathFunc: func(part *multipart.Part) string {
+			return filepath.Join(dir, "upload", part.FileName())
+		},
+		MaxSize: 16,
+	}).Handle())
+
+	newBody := func(field string, filename string, content string) (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("name", "Tom"))
+		fw, err := writer.CreateFormFile(field, filename)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		return body, writer.FormDataContentType()
+	}
+
+	testCases := []struct {
+		name     string
+		req      func() *http.Request
+		wantCode int
+		wantBody string
+		// 期望保存的文件和内容，为空表示不应该有文件
+		wantFile    string
+		wantContent string
+	}{
+		{
+			name: "upload",
+			req: func() *http.Request {
+				body, contentType := newBody("myfile", "avatar.png", "png")
+				req := httptest.NewRequest(http.MethodPost, "/upload", body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			wantCode:    http.StatusOK,
+			wantBody:    "上传成功",
+			wantFile:    "avatar.png",
+			wantContent: "png",
+		},
+		{
+			name: "too large",
+			req: func() *http.Request {
+				body, contentType := newBody("myfile", "large.csv", strings.Repeat("a", 17))
+				req := httptest.NewRequest(http.MethodPost, "/upload", body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantBody: "文件太大",
+			wantFile: "large.csv",
+		},
+		{
+			name: "not multipart",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("abc"))
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: "请求不是 multipart 表单",
+		},
+		{
+			name: "no file",
+			req: func() *http.Request {
+				body, contentType := newBody("otherfile", "a.csv", "abc")
+				req := httptest.NewRequest(http.MethodPost, "/upload", body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: "找不到上传的文件",
+		},
+		{
+			name: "truncated",
+			req: func() *http.Request {
+				body, contentType := newBody("myfile", "truncated.csv", "abc")
+				data := body.Bytes()
+				// 去掉结尾的 boundary
+				req := httptest.NewRequest(http.MethodPost, "/upload",
+					bytes.NewReader(data[:bytes.LastIndex(data, []byte("abc"))+3]))
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: "上传失败",
+			wantFile: "truncated.csv",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, tc.req())
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			if tc.wantFile == "" {
+				return
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "upload", tc.wantFile))
+			if tc.wantContent == "" {
+				// 失败的时候不会留下文件
+				assert.True(t, os.IsNotExist(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantContent, string(data))
+		})
+	}
+}
+
+type memoryStorage map[string]string
+
+func (m memoryStorage) Save(ctx context.Context, dst string, src io.Reader) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	m[dst] = string(data)
+	return nil
+}
+
+func TestFileUploader_Storage(t *testing.T) {
+	storage := memoryStorage{}
+	h := NewHTTPServer()
+	h.POST("/upload", (&FileUploader{
+		FileField: "myfile",
+		DstPathFunc: func(part *multipart.Part) string {
+			return "avatars/" + part.FileName()
+		},
+		Storage: storage,
+	}).Handle())
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fw, err := writer.CreateFormFile("myfile", "a.png")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("png"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, memoryStorage{"avatars/a.png": "png"}, storage)
+}
@@ -0,0 +1,97 @@
+package web
+
+import "net/http"
+
+// RouteGroup 路由分组
+// 组内的路由共享同一个前缀和同一组 Middleware
+type RouteGroup struct {
+	server *HTTPServer
+	// host 分组所属的域名，空字符串代表默认的路由树
+	host   string
+	prefix string
+	mdls   []Middleware
+}
+
+// Group 创建一个路由分组
+// prefix 必须以 / 开头，并且不能以 / 结尾
+// mdls 对分组内的所有路由生效，在路由自己的 Middleware 之前执行
+func (h *HTTPServer) Group(prefix string, mdls ...Middleware) *RouteGroup {
+	return newRouteGroup(h, "", prefix, nil, mdls)
+}
+
+// Group 创建子分组，子分组继承父分组的域名、前缀和 Middleware
+func (g *RouteGroup) Group(prefix string, mdls ...Middleware) *RouteGroup {
+	res := newRouteGroup(g.server, g.prefix, prefix, g.mdls, mdls)
+	res.host = g.host
+	return res
+}
+
+func newRouteGroup(server *HTTPServer, parentPrefix string, prefix string,
+	parentMdls []Middleware, mdls []Middleware) *RouteGroup {
+	if prefix == "" || prefix[0] != '/' {
+		panic("web: 分组前缀必须以 / 开头")
+	}
+	if prefix == "/" {
+		prefix = ""
+	} else if prefix[len(prefix)-1] == '/' {
+		panic("web: 分组前缀不能以 / 结尾")
+	}
+	// 复制一份，避免父子分组共享底层数组
+	groupMdls := make([]Middleware, 0, len(parentMdls)+len(mdls))
+	groupMdls = append(groupMdls, parentMdls...)
+	groupMdls = append(groupMdls, mdls...)
+	return &RouteGroup{
+		server: server,
+		prefix: parentPrefix + prefix,
+		mdls:   groupMdls,
+	}
+}
+
+// Use 追加分组的 Middleware，只对之后注册的路由生效
+func (g *RouteGroup) Use(mdls ...Middleware) {
+	g.mdls = append(g.mdls, mdls...)
+}
+
+// addRoute 拼接前缀之后委托给 router.addHostRoute，由它来做 path 的校验
+// path 为 / 的时候，注册的就是分组前缀本身
+func (g *RouteGroup) addRoute(method string, path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	if path == "" || path[0] != '/' {
+		panic("web: 路由必须以 / 开头")
+	}
+	fullPath := g.prefix + path
+	if path == "/" && g.prefix != "" {
+		fullPath = g.prefix
+	}
+	routeMdls := make([]Middleware, 0, len(g.mdls)+len(mdls))
+	routeMdls = append(routeMdls, g.mdls...)
+	routeMdls = append(routeMdls, mdls...)
+	return &Route{r: &g.server.router, n: g.server.addHostRoute(g.host, method, fullPath, handleFunc, routeMdls...)}
+}
+
+func (g *RouteGroup) Get(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.addRoute(http.MethodGet, path, handleFunc, mdls...)
+}
+
+func (g *RouteGroup) POST(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.addRoute(http.MethodPost, path, handleFunc, mdls...)
+}
+
+func (g *RouteGroup) PUT(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.addRoute(http.MethodPut, path, handleFunc, mdls...)
+}
+
+func (g *RouteGroup) DELETE(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.addRoute(http.MethodDelete, path, handleFunc, mdls...)
+}
+
+func (g *RouteGroup) PATCH(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.addRoute(http.MethodPatch, path, handleFunc, mdls...)
+}
+
+func (g *RouteGroup) HEAD(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.addRoute(http.MethodHead, path, handleFunc, mdls...)
+}
+
+func (g *RouteGroup) OPTIONS(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return g.addRoute(http.MethodOptions, path, handleFunc, mdls...)
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteGroup(t *testing.T) {
+	var logs []string
+	mdl := func(name string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				logs = append(logs, name)
+				next(ctx)
+			}
+		}
+	}
+	handler := func(name string) HandleFunc {
+		return func(ctx *Context) {
+			logs = append(logs, name)
+		}
+	}
+
+	h := NewHTTPServer()
+	api := h.Group("/api/v1", mdl("api"))
+	api.Get("/", handler("api root"))
+	user := api.Group("/user", mdl("user"))
+	user.Get("/:id", handler("user detail"), mdl("detail"))
+	user.DELETE("/:id", handler("user delete"))
+	order := api.Group("/order")
+	order.POST("/create", handler("order create"))
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		wantLogs []string
+	}{
+		{
+			name:     "group root",
+			method:   http.MethodGet,
+			path:     "/api/v1",
+			wantLogs: []string{"api", "api root"},
+		},
+		{
+			name:     "nested group",
+			method:   http.MethodGet,
+			path:     "/api/v1/user/123",
+			wantLogs: []string{"api", "user", "detail", "user detail"},
+		},
+		{
+			name:     "nested group delete",
+			method:   http.MethodDelete,
+			path:     "/api/v1/user/123",
+			wantLogs: []string{"api", "user", "user delete"},
+		},
+		{
+			// 兄弟分组之间的 Middleware 互不影响
+			name:     "sibling group",
+			method:   http.MethodPost,
+			path:     "/api/v1/order/create",
+			wantLogs: []string{"api", "order create"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs = nil
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.wantLogs, logs)
+		})
+	}
+
+	assert.Panicsf(t, func() {
+		h.Group("api")
+	}, "web: 分组前缀必须以 / 开头")
+	assert.Panicsf(t, func() {
+		h.Group("/api/")
+	}, "web: 分组前缀不能以 / 结尾")
+	assert.Panicsf(t, func() {
+		api.Get("user", handler("user"))
+	}, "web: 路由必须以 / 开头")
+	assert.Panicsf(t, func() {
+		api.Group("/user").Get("/:id", handler("user"))
+	}, "web: 路由冲突， 重复注册[/api/v1/user/:id]")
+}
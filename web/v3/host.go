@@ -0,0 +1,96 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Host 创建一个只对域名 host 生效的路由分组
+// host 可以是完整的域名，例如 api.example.com，
+// 也可以是通配符域名，例如 *.example.com，它匹配 example.com 的所有子域名
+// 匹配的时候忽略端口和大小写，完整的域名优先，通配符域名里面后缀更长的优先
+// 每个域名有自己的路由树，在里面找不到路由的时候，退回到默认的路由树
+func (h *HTTPServer) Host(host string, mdls ...Middleware) *RouteGroup {
+	g := newRouteGroup(h, "", "/", nil, mdls)
+	g.host = normalizeHostPattern(host)
+	return g
+}
+
+// normalizeHostPattern 校验并且规范化注册时的域名
+func normalizeHostPattern(host string) string {
+	if host == "" {
+		panic("web: 域名不能为空字符串")
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if strings.ContainsAny(host, ":/") {
+		panic(fmt.Sprintf("web: 非法域名 %s，不能包含端口或者路径", host))
+	}
+	idx := strings.IndexByte(host, '*')
+	if idx < 0 {
+		return host
+	}
+	if idx != 0 || len(host) < 3 || host[1] != '.' || strings.IndexByte(host[1:], '*') >= 0 {
+		panic(fmt.Sprintf("web: 非法域名 %s，通配符只能作为第一段，例如 *.example.com", host))
+	}
+	return host
+}
+
+// treesOrCreate 返回 host 对应的路由树，没有就创建
+// host 为空字符串的时候返回默认的路由树
+func (r *router) treesOrCreate(host string) map[string]*node {
+	if host == "" {
+		return r.trees
+	}
+	hosts, key := r.hosts, host
+	if host[0] == '*' {
+		hosts, key = r.wildcardHosts, host[1:]
+	}
+	trees, ok := hosts[key]
+	if !ok {
+		trees = map[string]*node{}
+		hosts[key] = trees
+	}
+	return trees
+}
+
+// hostTrees 找到请求的域名对应的路由树，没有找到返回 nil
+// host 是 Req.Host，可能带着端口
+func (r *router) hostTrees(host string) map[string]*node {
+	if len(r.hosts) == 0 && len(r.wildcardHosts) == 0 {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(stripPort(host), "."))
+	if trees, ok := r.hosts[host]; ok {
+		return trees
+	}
+	if len(r.wildcardHosts) == 0 {
+		return nil
+	}
+	// a.b.example.com 依次尝试 .b.example.com、.example.com 和 .com
+	for idx := strings.IndexByte(host, '.'); idx >= 0; {
+		if trees, ok := r.wildcardHosts[host[idx:]]; ok {
+			return trees
+		}
+		next := strings.IndexByte(host[idx+1:], '.')
+		if next < 0 {
+			break
+		}
+		idx += next + 1
+	}
+	return nil
+}
+
+// stripPort 去掉 example.com:8080 或者 [::1]:8080 里面的端口
+func stripPort(host string) string {
+	if host != "" && host[0] == '[' {
+		if end := strings.IndexByte(host, ']'); end > 0 {
+			return host[1:end]
+		}
+		return host
+	}
+	// 只有一个冒号才是端口，没有方括号的 IPv6 地址原样返回
+	if idx := strings.IndexByte(host, ':'); idx >= 0 && strings.LastIndexByte(host, ':') == idx {
+		return host[:idx]
+	}
+	return host
+}
@@ -0,0 +1,181 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPServer_Host(t *testing.T) {
+	handler := func(name string) HandleFunc {
+		return func(ctx *Context) {
+			ctx.RespString(http.StatusOK, name)
+		}
+	}
+	h := NewHTTPServer()
+	h.Get("/user", handler("default user"))
+	h.Get("/order", handler("default order"))
+	api := h.Host("API.example.com")
+	api.Get("/user", handler("api user"))
+	api.Group("/v1").Get("/user/:id", func(ctx *Context) {
+		id, _ := ctx.PathValue("id").AsString()
+		ctx.RespString(http.StatusOK, "api v1 user "+id)
+	})
+	api.POST("/order", handler("api create order"))
+	h.Host("*.example.com").Get("/user", handler("wildcard user"))
+	h.Host("*.shop.example.com").Get("/user", handler("shop user"))
+
+	testCases := []struct {
+		name      string
+		method    string
+		host      string
+		path      string
+		wantCode  int
+		wantBody  string
+		wantAllow string
+	}{
+		{
+			name:     "default",
+			method:   http.MethodGet,
+			host:     "localhost",
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "default user",
+		},
+		{
+			// 忽略端口和大小写
+			name:     "exact host with port",
+			method:   http.MethodGet,
+			host:     "Api.Example.com:8080",
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "api user",
+		},
+		{
+			name:     "host group",
+			method:   http.MethodGet,
+			host:     "api.example.com",
+			path:     "/v1/user/123",
+			wantCode: http.StatusOK,
+			wantBody: "api v1 user 123",
+		},
+		{
+			// 域名下面没有的路由使用默认的路由树
+			name:     "fallback to default",
+			method:   http.MethodGet,
+			host:     "api.example.com",
+			path:     "/order",
+			wantCode: http.StatusOK,
+			wantBody: "default order",
+		},
+		{
+			name:     "wildcard host",
+			method:   http.MethodGet,
+			host:     "www.example.com",
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "wildcard user",
+		},
+		{
+			// 后缀更长的通配符域名优先
+			name:     "longer wildcard host",
+			method:   http.MethodGet,
+			host:     "a.shop.example.com",
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "shop user",
+		},
+		{
+			// 通配符不匹配域名本身
+			name:     "wildcard not match root domain",
+			method:   http.MethodGet,
+			host:     "example.com",
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "default user",
+		},
+		{
+			// 405 的 Allow 同时考虑域名和默认的路由树
+			name:      "method not allowed",
+			method:    http.MethodPut,
+			host:      "api.example.com",
+			path:      "/order",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantBody:  "METHOD NOT ALLOWED",
+			wantAllow: "GET, HEAD, OPTIONS, POST",
+		},
+		{
+			name:     "not found",
+			method:   http.MethodGet,
+			host:     "www.example.com",
+			path:     "/v1/user/123",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Host = tc.host
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantAllow, recorder.Header().Get("Allow"))
+		})
+	}
+
+	var routes []string
+	for _, route := range h.Routes() {
+		routes = append(routes, route.Host+" "+route.Method+" "+route.Pattern)
+	}
+	assert.Equal(t, []string{
+		" GET /order",
+		" GET /user",
+		"*.example.com GET /user",
+		"*.shop.example.com GET /user",
+		"api.example.com POST /order",
+		"api.example.com GET /user",
+		"api.example.com GET /v1/user/:id",
+	}, routes)
+}
+
+func TestHTTPServer_HostPanic(t *testing.T) {
+	h := NewHTTPServer()
+	assert.PanicsWithValue(t, "web: 域名不能为空字符串", func() {
+		h.Host("")
+	})
+	assert.PanicsWithValue(t, "web: 非法域名 a.*.com，通配符只能作为第一段，例如 *.example.com", func() {
+		h.Host("a.*.com")
+	})
+	assert.PanicsWithValue(t, "web: 非法域名 *example.com，通配符只能作为第一段，例如 *.example.com", func() {
+		h.Host("*example.com")
+	})
+	assert.PanicsWithValue(t, "web: 非法域名 example.com:8080，不能包含端口或者路径", func() {
+		h.Host("example.com:8080")
+	})
+	h.Host("example.com").Get("/user", func(ctx *Context) {})
+	assert.PanicsWithValue(t, "web: 路由冲突， 重复注册[/user]", func() {
+		h.Host("Example.com").Get("/user", func(ctx *Context) {})
+	})
+}
+
+func TestStripPort(t *testing.T) {
+	testCases := []struct {
+		host string
+		want string
+	}{
+		{host: "example.com", want: "example.com"},
+		{host: "example.com:8080", want: "example.com"},
+		{host: "[::1]:8080", want: "::1"},
+		{host: "[::1]", want: "::1"},
+		{host: "::1", want: "::1"},
+		{host: "", want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			assert.Equal(t, tc.want, stripPort(tc.host))
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package web
+
+// Middleware 函数式的责任链模式
+// 也叫做洋葱模式：next 是下一个要执行的 HandleFunc，
+// 返回值是包装之后的 HandleFunc
+type Middleware func(next HandleFunc) HandleFunc
+
+// buildChain 用 mdls 从后往前包装 handler，
+// 所以 mdls[0] 在最外层，最先执行
+func buildChain(handler HandleFunc, mdls []Middleware) HandleFunc {
+	for i := len(mdls) - 1; i >= 0; i-- {
+		handler = mdls[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,66 @@
+package accesslog
+
+import (
+	"encoding/json"
+	web "leanring-go/web/v3"
+	"log"
+	"time"
+)
+
+// MiddlewareBuilder 记录访问日志，每个请求输出一条 JSON
+type MiddlewareBuilder struct {
+	logFunc func(log string)
+}
+
+func NewMiddlewareBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		logFunc: func(accessLog string) {
+			log.Println(accessLog)
+		},
+	}
+}
+
+// LogFunc 设置输出日志的方法
+func (m *MiddlewareBuilder) LogFunc(fn func(log string)) *MiddlewareBuilder {
+	m.logFunc = fn
+	return m
+}
+
+func (m *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			start := time.Now()
+			// 直接使用 ctx.Resp 写的响应也要统计进来
+			resp := ctx.Resp
+			recorder := web.NewResponseRecorder(resp)
+			ctx.Resp = recorder
+			defer func() {
+				ctx.Resp = resp
+				l := accessLog{
+					Host:       ctx.Req.Host,
+					Route:      ctx.MatchedRoute,
+					HTTPMethod: ctx.Req.Method,
+					Path:       ctx.Req.URL.Path,
+					StatusCode: recorder.StatusCode(ctx),
+					Duration:   time.Since(start),
+					RespSize:   recorder.Size(ctx),
+				}
+				data, _ := json.Marshal(l)
+				m.logFunc(string(data))
+			}()
+			next(ctx)
+		}
+	}
+}
+
+type accessLog struct {
+	Host string `json:"host,omitempty"`
+	// Route 命中的路由，例如 /order/detail/:id
+	Route      string `json:"route,omitempty"`
+	HTTPMethod string `json:"http_method,omitempty"`
+	Path       string `json:"path,omitempty"`
+	StatusCode int    `json:"status_code"`
+	// Duration 单位是纳秒
+	Duration time.Duration `json:"duration"`
+	RespSize int           `json:"resp_size"`
+}
@@ -0,0 +1,154 @@
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	web "leanring-go/web/v3"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	var logs []string
+	builder := NewMiddlewareBuilder().LogFunc(func(log string) {
+		logs = append(logs, log)
+	})
+	h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	h.Get("/order/detail/:id", func(ctx *web.Context) {
+		ctx.RespString(http.StatusOK, "order detail")
+	})
+	h.Get("/data", func(ctx *web.Context) {
+		// 只设置了响应体，客户端收到的是 200
+		ctx.RespData = []byte("data")
+	})
+	h.Get("/raw", func(ctx *web.Context) {
+		ctx.Resp.WriteHeader(http.StatusAccepted)
+		_, _ = ctx.Resp.Write([]byte("raw"))
+	})
+
+	testCases := []struct {
+		name    string
+		path    string
+		wantLog accessLog
+	}{
+		{
+			name: "matched route",
+			path: "/order/detail/123",
+			wantLog: accessLog{
+				Host:       "example.com",
+				Route:      "/order/detail/:id",
+				HTTPMethod: http.MethodGet,
+				Path:       "/order/detail/123",
+				StatusCode: http.StatusOK,
+				RespSize:   len("order detail"),
+			},
+		},
+		{
+			name: "no status code",
+			path: "/data",
+			wantLog: accessLog{
+				Host:       "example.com",
+				Route:      "/data",
+				HTTPMethod: http.MethodGet,
+				Path:       "/data",
+				StatusCode: http.StatusOK,
+				RespSize:   len("data"),
+			},
+		},
+		{
+			name: "raw",
+			path: "/raw",
+			wantLog: accessLog{
+				Host:       "example.com",
+				Route:      "/raw",
+				HTTPMethod: http.MethodGet,
+				Path:       "/raw",
+				StatusCode: http.StatusAccepted,
+				RespSize:   len("raw"),
+			},
+		},
+		{
+			name: "not found",
+			path: "/user",
+			wantLog: accessLog{
+				Host:       "example.com",
+				HTTPMethod: http.MethodGet,
+				Path:       "/user",
+				StatusCode: http.StatusNotFound,
+				RespSize:   len("NOT FOUND"),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs = nil
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Len(t, logs, 1)
+			var l accessLog
+			require.NoError(t, json.Unmarshal([]byte(logs[0]), &l))
+			assert.True(t, l.Duration > 0)
+			l.Duration = 0
+			assert.Equal(t, tc.wantLog, l)
+		})
+	}
+}
+
+func TestMiddlewareBuilder_Streaming(t *testing.T) {
+	// 日志是在服务器的 goroutine 里面输出的
+	logs := make(chan string, 2)
+	builder := NewMiddlewareBuilder().LogFunc(func(log string) {
+		logs <- log
+	})
+	h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	h.Get("/stream", func(ctx *web.Context) {
+		// 不在测试的 goroutine 里面，不能使用 require
+		flusher, ok := ctx.Resp.(http.Flusher)
+		if !assert.True(t, ok) {
+			return
+		}
+		_, _ = ctx.Resp.Write([]byte("chunk"))
+		flusher.Flush()
+	})
+	h.Get("/upgrade", func(ctx *web.Context) {
+		hijacker, ok := ctx.Resp.(http.Hijacker)
+		if !assert.True(t, ok) {
+			return
+		}
+		conn, rw, err := hijacker.Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		_ = rw.Flush()
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/upgrade", nil)
+	require.NoError(t, err)
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, req.Write(conn))
+	resp, err = http.ReadResponse(bufio.NewReader(conn), req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	var l accessLog
+	require.NoError(t, json.Unmarshal([]byte(<-logs), &l))
+	assert.Equal(t, http.StatusOK, l.StatusCode)
+	assert.Equal(t, len("chunk"), l.RespSize)
+	require.NoError(t, json.Unmarshal([]byte(<-logs), &l))
+	assert.Equal(t, http.StatusSwitchingProtocols, l.StatusCode)
+}
@@ -0,0 +1,147 @@
+package cors
+
+import (
+	web "leanring-go/web/v3"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MiddlewareBuilder 跨域资源共享（CORS）
+// 预检请求直接在 Middleware 里面返回，所以不需要注册 OPTIONS 路由，
+// 需要注册成服务器级别的 Middleware，这样没有命中路由的预检请求也能处理
+type MiddlewareBuilder struct {
+	// AllowOrigins 允许的来源，例如 https://example.com
+	// * 代表允许所有来源，也可以使用通配符，例如 https://*.example.com
+	AllowOrigins []string
+	// AllowOriginFunc 自定义的判断，AllowOrigins 没有匹配上的时候使用
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods 预检请求返回的 Access-Control-Allow-Methods
+	AllowMethods []string
+	// AllowHeaders 预检请求返回的 Access-Control-Allow-Headers
+	// 为空的时候原样返回 Access-Control-Request-Headers
+	AllowHeaders []string
+	// ExposeHeaders 允许浏览器读取的响应头
+	ExposeHeaders []string
+	// AllowCredentials 是否允许携带 cookie
+	// 允许的时候 Access-Control-Allow-Origin 不能是 *，会返回请求的来源
+	AllowCredentials bool
+	// MaxAge 预检请求的结果可以缓存多久，0 代表不设置
+	MaxAge time.Duration
+}
+
+func NewMiddlewareBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		AllowMethods: []string{
+			http.MethodGet, http.MethodPost, http.MethodPut,
+			http.MethodPatch, http.MethodDelete, http.MethodHead,
+		},
+	}
+}
+
+func (m *MiddlewareBuilder) Build() web.Middleware {
+	allowAll := false
+	var exact []string
+	var patterns [][2]string
+	for _, origin := range m.AllowOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		if prefix, suffix, ok := strings.Cut(origin, "*"); ok {
+			patterns = append(patterns, [2]string{prefix, suffix})
+			continue
+		}
+		exact = append(exact, origin)
+	}
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		lower := strings.ToLower(origin)
+		for _, o := range exact {
+			if o == lower {
+				return true
+			}
+		}
+		for _, p := range patterns {
+			if len(lower) > len(p[0])+len(p[1]) &&
+				strings.HasPrefix(lower, p[0]) && strings.HasSuffix(lower, p[1]) {
+				return true
+			}
+		}
+		return m.AllowOriginFunc != nil && m.AllowOriginFunc(origin)
+	}
+	// 允许所有来源并且不携带 cookie 的时候，响应和来源无关
+	staticOrigin := allowAll && !m.AllowCredentials
+	allowMethods := strings.Join(m.AllowMethods, ", ")
+	allowHeaders := strings.Join(m.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(m.ExposeHeaders, ", ")
+	maxAge := ""
+	if m.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(m.MaxAge/time.Second), 10)
+	}
+
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			header := ctx.Resp.Header()
+			if !staticOrigin {
+				// 响应随着来源变化，缓存需要区分，没有 Origin 的请求也一样
+				header.Add("Vary", "Origin")
+			}
+			origin := ctx.Req.Header.Get("Origin")
+			preflight := ctx.Req.Method == http.MethodOptions &&
+				ctx.Req.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+			if origin == "" {
+				next(ctx)
+				return
+			}
+			if !allowed(origin) {
+				if preflight {
+					ctx.RespStatusCode = http.StatusForbidden
+					ctx.RespData = []byte("FORBIDDEN")
+					return
+				}
+				// 普通请求照常处理，没有 CORS 响应头，浏览器会拒绝读取响应
+				next(ctx)
+				return
+			}
+
+			if staticOrigin {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if m.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if exposeHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				next(ctx)
+				return
+			}
+
+			// 预检请求，不需要执行后面的 Middleware 和 handler
+			if allowMethods != "" {
+				header.Set("Access-Control-Allow-Methods", allowMethods)
+			}
+			if allowHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", allowHeaders)
+			} else if reqHeaders := ctx.Req.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", reqHeaders)
+			}
+			if maxAge != "" {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			ctx.RespStatusCode = http.StatusNoContent
+		}
+	}
+}
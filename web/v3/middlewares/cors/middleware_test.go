@@ -0,0 +1,229 @@
+package cors
+
+import (
+	"github.com/stretchr/testify/assert"
+	web "leanring-go/web/v3"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	builder := NewMiddlewareBuilder()
+	builder.AllowOrigins = []string{"https://example.com", "https://*.example.org"}
+	builder.AllowOriginFunc = func(origin string) bool {
+		return origin == "http://localhost:3000"
+	}
+	builder.AllowHeaders = []string{"Content-Type", "Authorization"}
+	builder.ExposeHeaders = []string{"X-Request-Id"}
+	builder.AllowCredentials = true
+	builder.MaxAge = 10 * time.Minute
+
+	h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	h.Get("/user", func(ctx *web.Context) {
+		ctx.RespString(http.StatusOK, "hello, user")
+	})
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		wantCode   int
+		wantBody   string
+		wantHeader map[string]string
+		wantVary   []string
+	}{
+		{
+			name:     "no origin",
+			method:   http.MethodGet,
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "hello, user",
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+			wantVary: []string{"Origin"},
+		},
+		{
+			name:   "simple request",
+			method: http.MethodGet,
+			path:   "/user",
+			header: map[string]string{
+				"Origin": "https://example.com",
+			},
+			wantCode: http.StatusOK,
+			wantBody: "hello, user",
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-Id",
+				"Access-Control-Allow-Methods":     "",
+			},
+			wantVary: []string{"Origin"},
+		},
+		{
+			name:   "pattern origin",
+			method: http.MethodGet,
+			path:   "/user",
+			header: map[string]string{
+				"Origin": "https://app.example.org",
+			},
+			wantCode: http.StatusOK,
+			wantBody: "hello, user",
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin": "https://app.example.org",
+			},
+			wantVary: []string{"Origin"},
+		},
+		{
+			name:   "origin func",
+			method: http.MethodGet,
+			path:   "/user",
+			header: map[string]string{
+				"Origin": "http://localhost:3000",
+			},
+			wantCode: http.StatusOK,
+			wantBody: "hello, user",
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin": "http://localhost:3000",
+			},
+			wantVary: []string{"Origin"},
+		},
+		{
+			// 不允许的来源照常处理，但是没有 CORS 响应头
+			name:   "disallowed simple request",
+			method: http.MethodGet,
+			path:   "/user",
+			header: map[string]string{
+				"Origin": "https://example.org",
+			},
+			wantCode: http.StatusOK,
+			wantBody: "hello, user",
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+			wantVary: []string{"Origin"},
+		},
+		{
+			// 没有注册 OPTIONS 路由也能处理预检请求
+			name:   "preflight",
+			method: http.MethodOptions,
+			path:   "/user",
+			header: map[string]string{
+				"Origin":                         "https://example.com",
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "content-type",
+			},
+			wantCode: http.StatusNoContent,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE, HEAD",
+				"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+				"Access-Control-Max-Age":           "600",
+				"Access-Control-Expose-Headers":    "",
+				"Allow":                            "",
+			},
+			wantVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			// 路由不存在也一样
+			name:   "preflight not found",
+			method: http.MethodOptions,
+			path:   "/order",
+			header: map[string]string{
+				"Origin":                        "https://example.com",
+				"Access-Control-Request-Method": http.MethodPost,
+			},
+			wantCode: http.StatusNoContent,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin": "https://example.com",
+			},
+			wantVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:   "disallowed preflight",
+			method: http.MethodOptions,
+			path:   "/user",
+			header: map[string]string{
+				"Origin":                        "https://example.org",
+				"Access-Control-Request-Method": http.MethodPut,
+			},
+			wantCode: http.StatusForbidden,
+			wantBody: "FORBIDDEN",
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+			wantVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			// 不是预检请求的 OPTIONS 交给框架处理
+			name:   "plain options",
+			method: http.MethodOptions,
+			path:   "/user",
+			header: map[string]string{
+				"Origin": "https://example.com",
+			},
+			wantCode: http.StatusNoContent,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin": "https://example.com",
+				"Allow":                       "GET, HEAD, OPTIONS",
+			},
+			wantVary: []string{"Origin"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			for k, v := range tc.wantHeader {
+				assert.Equal(t, v, recorder.Header().Get(k), k)
+			}
+			assert.Equal(t, tc.wantVary, recorder.Header().Values("Vary"))
+		})
+	}
+}
+
+func TestMiddlewareBuilder_AllowAll(t *testing.T) {
+	builder := NewMiddlewareBuilder()
+	builder.AllowOrigins = []string{"*"}
+	h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	h.Get("/user", func(ctx *web.Context) {
+		ctx.RespString(http.StatusOK, "hello, user")
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/user", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "X-Token")
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	// 没有配置 AllowHeaders 的时候原样返回
+	assert.Equal(t, "X-Token", recorder.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "", recorder.Header().Get("Access-Control-Max-Age"))
+	// 响应和来源无关，不需要 Vary: Origin
+	assert.Equal(t, "Access-Control-Request-Method, Access-Control-Request-Headers",
+		strings.Join(recorder.Header().Values("Vary"), ", "))
+
+	// 携带 cookie 的时候不能返回 *
+	builder.AllowCredentials = true
+	h = web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	h.Get("/user", func(ctx *web.Context) {})
+	req = httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set("Origin", "https://example.com")
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	assert.Equal(t, "https://example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{"Origin"}, recorder.Header().Values("Vary"))
+}
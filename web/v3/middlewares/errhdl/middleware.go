@@ -0,0 +1,42 @@
+package errhdl
+
+import (
+	web "leanring-go/web/v3"
+)
+
+// MiddlewareBuilder 根据响应码替换响应，例如返回自定义的 404 页面
+// 依赖于框架在所有 Middleware 执行完毕之后才写回响应
+type MiddlewareBuilder struct {
+	// ContentType 替换响应的时候设置的 Content-Type，为空就不修改
+	ContentType string
+	// 响应码到响应的映射
+	resp map[int][]byte
+}
+
+func NewMiddlewareBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		resp: make(map[int][]byte, 8),
+	}
+}
+
+// AddCode 注册响应码对应的响应
+func (m *MiddlewareBuilder) AddCode(status int, data []byte) *MiddlewareBuilder {
+	m.resp[status] = data
+	return m
+}
+
+func (m *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			next(ctx)
+			resp, ok := m.resp[ctx.RespStatusCode]
+			if !ok {
+				return
+			}
+			if m.ContentType != "" {
+				ctx.Resp.Header().Set("Content-Type", m.ContentType)
+			}
+			ctx.RespData = resp
+		}
+	}
+}
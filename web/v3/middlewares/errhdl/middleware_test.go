@@ -0,0 +1,77 @@
+package errhdl
+
+import (
+	"github.com/stretchr/testify/assert"
+	web "leanring-go/web/v3"
+	"leanring-go/web/v3/middlewares/recovery"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	builder := NewMiddlewareBuilder().
+		AddCode(http.StatusNotFound, []byte("<h1>页面找不到了</h1>")).
+		AddCode(http.StatusInternalServerError, []byte("<h1>服务器出错了</h1>"))
+	builder.ContentType = "text/html; charset=utf-8"
+	rb := recovery.NewMiddlewareBuilder()
+	rb.LogFunc = func(ctx *web.Context, err any, stack []byte) {}
+	// recovery 在 errhdl 里面，这样 errhdl 能够看到 500
+	h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build(), rb.Build()))
+	h.Get("/user", func(ctx *web.Context) {
+		ctx.RespString(http.StatusOK, "hello, user")
+	})
+	h.Get("/panic", func(ctx *web.Context) {
+		panic("出错了")
+	})
+	h.Get("/bad", func(ctx *web.Context) {
+		ctx.RespString(http.StatusBadRequest, "bad request")
+	})
+
+	testCases := []struct {
+		name            string
+		path            string
+		wantCode        int
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "ok",
+			path:            "/user",
+			wantCode:        http.StatusOK,
+			wantBody:        "hello, user",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "not found",
+			path:            "/order",
+			wantCode:        http.StatusNotFound,
+			wantBody:        "<h1>页面找不到了</h1>",
+			wantContentType: "text/html; charset=utf-8",
+		},
+		{
+			name:            "panic",
+			path:            "/panic",
+			wantCode:        http.StatusInternalServerError,
+			wantBody:        "<h1>服务器出错了</h1>",
+			wantContentType: "text/html; charset=utf-8",
+		},
+		{
+			// 没有注册的响应码不处理
+			name:            "bad request",
+			path:            "/bad",
+			wantCode:        http.StatusBadRequest,
+			wantBody:        "bad request",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantContentType, recorder.Header().Get("Content-Type"))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package opentelemetry
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	web "leanring-go/web/v3"
+	"net/http"
+)
+
+const instrumentationName = "github.com/xuqil/learning-go/web/v3/middlewares/opentelemetry"
+
+// MiddlewareBuilder 链路追踪
+// 从请求头里面提取出链路信息，并且把 span 放进 ctx.Req.Context() 里面，
+// 后续发起的 gRPC 调用使用这个 context 就能接上同一条链路
+type MiddlewareBuilder struct {
+	Tracer trace.Tracer
+	// Propagator 为 nil 的时候使用 W3C 的 trace context
+	Propagator propagation.TextMapPropagator
+}
+
+func (b *MiddlewareBuilder) Build() web.Middleware {
+	if b.Tracer == nil {
+		b.Tracer = otel.GetTracerProvider().Tracer(instrumentationName)
+	}
+	if b.Propagator == nil {
+		b.Propagator = propagation.TraceContext{}
+	}
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			reqCtx := b.Propagator.Extract(ctx.Req.Context(), propagation.HeaderCarrier(ctx.Req.Header))
+			// 这时候还不知道命中的路由，先用 unknown，处理完了再改
+			spanCtx, span := b.Tracer.Start(reqCtx, "unknown", trace.WithSpanKind(trace.SpanKindServer))
+			defer span.End()
+
+			span.SetAttributes(
+				attribute.String("http.method", ctx.Req.Method),
+				attribute.String("http.url", ctx.Req.URL.String()),
+				attribute.String("http.scheme", ctx.Req.URL.Scheme),
+				attribute.String("http.host", ctx.Req.Host),
+			)
+			ctx.Req = ctx.Req.WithContext(spanCtx)
+
+			// 业务代码可能直接写 ctx.Resp，例如 http.ServeContent 返回的 304、206
+			resp := ctx.Resp
+			recorder := web.NewResponseRecorder(resp)
+			ctx.Resp = recorder
+			defer func() {
+				ctx.Resp = resp
+			}()
+
+			next(ctx)
+
+			if ctx.MatchedRoute != "" {
+				span.SetName(ctx.MatchedRoute)
+				span.SetAttributes(attribute.String("http.route", ctx.MatchedRoute))
+			}
+			status := recorder.StatusCode(ctx)
+			span.SetAttributes(attribute.Int("http.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package opentelemetry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	web "leanring-go/web/v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	builder := &MiddlewareBuilder{
+		Tracer: tp.Tracer(instrumentationName),
+	}
+	h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	var handlerSpanCtx trace.SpanContext
+	h.Get("/order/detail/:id", func(ctx *web.Context) {
+		handlerSpanCtx = trace.SpanContextFromContext(ctx.Req.Context())
+		ctx.RespString(http.StatusOK, "order detail")
+	})
+	h.Get("/panic", func(ctx *web.Context) {
+		ctx.RespString(http.StatusInternalServerError, "error")
+	})
+	h.Get("/cached", func(ctx *web.Context) {
+		ctx.Resp.WriteHeader(http.StatusNotModified)
+	})
+	h.Get("/raw", func(ctx *web.Context) {
+		ctx.Resp.WriteHeader(http.StatusBadGateway)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/order/detail/123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "/order/detail/:id", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	// 接上了上游的链路
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	// handler 里面拿到的就是这个 span
+	assert.Equal(t, span.SpanContext().SpanID(), handlerSpanCtx.SpanID())
+	assert.Contains(t, span.Attributes(), attribute.String("http.route", "/order/detail/:id"))
+	assert.Contains(t, span.Attributes(), attribute.Int("http.status_code", http.StatusOK))
+	assert.Equal(t, codes.Unset, span.Status().Code)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	spans = recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "/panic", spans[1].Name())
+	assert.False(t, spans[1].Parent().IsValid())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+
+	// 没有命中路由
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user", nil))
+	spans = recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "unknown", spans[2].Name())
+	assert.Contains(t, spans[2].Attributes(), attribute.Int("http.status_code", http.StatusNotFound))
+
+	// 直接写 ctx.Resp 的状态码
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/cached", nil))
+	spans = recorder.Ended()
+	require.Len(t, spans, 4)
+	assert.Contains(t, spans[3].Attributes(), attribute.Int("http.status_code", http.StatusNotModified))
+	assert.Equal(t, codes.Unset, spans[3].Status().Code)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/raw", nil))
+	spans = recorder.Ended()
+	require.Len(t, spans, 5)
+	assert.Contains(t, spans[4].Attributes(), attribute.Int("http.status_code", http.StatusBadGateway))
+	assert.Equal(t, codes.Error, spans[4].Status().Code)
+}
@@ -0,0 +1,91 @@
+package prometheus
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"leanring-go/micro/observability"
+	web "leanring-go/web/v3"
+	"strconv"
+	"time"
+)
+
+// MiddlewareBuilder 采集 HTTP 服务端的指标
+// 指标的命名和 micro/observability/metrics.ServerMetricsBuilder 保持一致，
+// 这样 HTTP 和 gRPC 可以使用同一套监控面板
+type MiddlewareBuilder struct {
+	Namespace string
+	Subsystem string
+	Port      int
+	// Registerer 为 nil 的时候使用 prometheus.DefaultRegisterer
+	Registerer prometheus.Registerer
+}
+
+func (b *MiddlewareBuilder) Build() web.Middleware {
+	addr := observability.GetOutboundIP()
+	if b.Port != 0 {
+		addr = fmt.Sprintf("%s:%d", addr, b.Port)
+	}
+	constLabels := map[string]string{
+		"component": "http_server",
+		"address":   addr,
+	}
+	reqGauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   b.Namespace,
+		Subsystem:   b.Subsystem,
+		Name:        "active_request_cnt",
+		Help:        "当前正在处理的请求数量",
+		ConstLabels: constLabels,
+	}, []string{"method"})
+	reqCnt := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   b.Namespace,
+		Subsystem:   b.Subsystem,
+		Name:        "request_cnt",
+		Help:        "请求总数",
+		ConstLabels: constLabels,
+	}, []string{"pattern", "method", "status"})
+	response := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:   b.Namespace,
+		Subsystem:   b.Subsystem,
+		Name:        "resp_duration",
+		Help:        "响应耗时",
+		ConstLabels: constLabels,
+		Objectives: map[float64]float64{
+			0.5:   0.01,
+			0.75:  0.01,
+			0.90:  0.01,
+			0.99:  0.001,
+			0.999: 0.0001,
+		},
+	}, []string{"pattern", "method", "status"})
+	registerer := b.Registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	registerer.MustRegister(reqGauge, reqCnt, response)
+
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			startTime := time.Now()
+			method := ctx.Req.Method
+			reqGauge.WithLabelValues(method).Add(1)
+			// 直接使用 ctx.Resp 写的响应码也要统计进来，例如 http.ServeContent 返回的 304
+			resp := ctx.Resp
+			recorder := web.NewResponseRecorder(resp)
+			ctx.Resp = recorder
+			defer func() {
+				ctx.Resp = resp
+				reqGauge.WithLabelValues(method).Add(-1)
+				// 没有命中路由的请求统一归到 unknown，避免原始路径导致标签爆炸
+				pattern := ctx.MatchedRoute
+				if pattern == "" {
+					pattern = "unknown"
+				}
+				statusStr := strconv.Itoa(recorder.StatusCode(ctx))
+				reqCnt.WithLabelValues(pattern, method, statusStr).Inc()
+				response.WithLabelValues(pattern, method, statusStr).
+					Observe(float64(time.Since(startTime).Milliseconds()))
+			}()
+			next(ctx)
+		}
+	}
+}
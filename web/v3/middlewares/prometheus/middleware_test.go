@@ -0,0 +1,68 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	web "leanring-go/web/v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	builder := &MiddlewareBuilder{
+		Namespace:  "learning_go",
+		Subsystem:  "web",
+		Port:       8081,
+		Registerer: registry,
+	}
+	h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	h.Get("/user/:id", func(ctx *web.Context) {
+		ctx.RespString(http.StatusOK, "hello, user")
+	})
+	h.Get("/raw", func(ctx *web.Context) {
+		_, _ = ctx.Resp.Write([]byte("raw"))
+	})
+	h.Get("/cached", func(ctx *web.Context) {
+		// 例如 http.ServeContent 返回的 304
+		ctx.Resp.WriteHeader(http.StatusNotModified)
+	})
+
+	for _, path := range []string{"/user/1", "/user/2", "/raw", "/cached", "/order"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	cnts := map[string]float64{}
+	for _, family := range families {
+		switch family.GetName() {
+		case "learning_go_web_active_request_cnt":
+			// 请求都处理完了
+			require.Len(t, family.GetMetric(), 1)
+			assert.Equal(t, float64(0), family.GetMetric()[0].GetGauge().GetValue())
+			continue
+		case "learning_go_web_resp_duration":
+			assert.Len(t, family.GetMetric(), 4)
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			assert.Equal(t, "http_server", labels["component"])
+			key := labels["pattern"] + " " + labels["method"] + " " + labels["status"]
+			cnts[key] = metric.GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"/user/:id GET 200": 2,
+		"/raw GET 200":      1,
+		"/cached GET 304":   1,
+		"unknown GET 404":   1,
+	}, cnts)
+	assert.Len(t, families, 3)
+}
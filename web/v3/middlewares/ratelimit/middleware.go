@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"leanring-go/micro/ratelimit"
+	web "leanring-go/web/v3"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MiddlewareBuilder 限流，被限流的请求返回 429 和 Retry-After
+// 限流算法来自于 micro/ratelimit，和 gRPC 的拦截器是同一套实现，
+// 例如 ratelimit.NewTokenBucketLimiter、ratelimit.NewRedisSlideWindowLimiter
+type MiddlewareBuilder struct {
+	limiter ratelimit.Limiter
+	keyFunc KeyFunc
+	logFunc func(ctx *web.Context, err error)
+}
+
+// KeyFunc 决定限流对象，key 相同的请求共用一个阈值
+type KeyFunc func(ctx *web.Context) string
+
+// NewMiddlewareBuilder 默认按照客户端的 IP 限流
+func NewMiddlewareBuilder(limiter ratelimit.Limiter) *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		limiter: limiter,
+		keyFunc: KeyByIP(),
+		logFunc: func(ctx *web.Context, err error) {
+			log.Printf("web: 限流失败 %s %s %v", ctx.Req.Method, ctx.Req.URL.Path, err)
+		},
+	}
+}
+
+// KeyFunc 设置限流对象，例如 KeyByRoute、KeyByHeader
+func (m *MiddlewareBuilder) KeyFunc(fn KeyFunc) *MiddlewareBuilder {
+	m.keyFunc = fn
+	return m
+}
+
+// LogFunc 设置限流器出错时输出日志的方法
+func (m *MiddlewareBuilder) LogFunc(fn func(ctx *web.Context, err error)) *MiddlewareBuilder {
+	m.logFunc = fn
+	return m
+}
+
+func (m *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			limited, retryAfter, err := m.limiter.Limit(ctx.Req.Context(), m.keyFunc(ctx))
+			if err != nil {
+				// 和 gRPC 的拦截器一样，限流器出错的时候拒绝请求，例如 Redis 不可用
+				m.logFunc(ctx, err)
+				ctx.RespStatusCode = http.StatusInternalServerError
+				ctx.RespData = []byte("INTERNAL SERVER ERROR")
+				return
+			}
+			if limited {
+				ctx.Resp.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+				ctx.RespStatusCode = http.StatusTooManyRequests
+				ctx.RespData = []byte("TOO MANY REQUESTS")
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// retryAfterSeconds Retry-After 的单位是秒，向上取整，最少一秒
+func retryAfterSeconds(retryAfter time.Duration) string {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// KeyByIP 按照客户端的 IP 限流，IP 来自于 RemoteAddr
+// 在反向代理后面的时候 RemoteAddr 是代理的地址，可以使用 KeyByHeader("X-Real-IP")，
+// 前提是代理会覆盖这个请求头，否则客户端可以伪造它来绕过限流
+func KeyByIP() KeyFunc {
+	return func(ctx *web.Context) string {
+		ip, _, err := net.SplitHostPort(ctx.Req.RemoteAddr)
+		if err != nil {
+			return ctx.Req.RemoteAddr
+		}
+		return ip
+	}
+}
+
+// KeyByRoute 按照命中的路由限流，例如 GET /user/:id，这个路由的所有请求共用一个阈值
+// 需要注册成路由上的 Middleware，服务器级别的 Middleware 执行的时候还没有查找路由，
+// 这个时候使用请求的路径
+func KeyByRoute() KeyFunc {
+	return func(ctx *web.Context) string {
+		route := ctx.MatchedRoute
+		if route == "" {
+			route = ctx.Req.URL.Path
+		}
+		return ctx.Req.Method + " " + route
+	}
+}
+
+// KeyByHeader 按照请求头限流，例如 X-API-Key，没有这个请求头的请求共用一个阈值
+func KeyByHeader(name string) KeyFunc {
+	return func(ctx *web.Context) string {
+		return ctx.Req.Header.Get(name)
+	}
+}
@@ -0,0 +1,202 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"leanring-go/micro/ratelimit"
+	web "leanring-go/web/v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name    string
+		limiter ratelimit.Limiter
+
+		wantCode       int
+		wantBody       string
+		wantRetryAfter string
+		wantErr        error
+	}{
+		{
+			name:     "pass",
+			limiter:  mockLimiter{},
+			wantCode: http.StatusOK,
+			wantBody: "hello, user",
+		},
+		{
+			name:           "limited",
+			limiter:        mockLimiter{limited: true, retryAfter: time.Millisecond * 1500},
+			wantCode:       http.StatusTooManyRequests,
+			wantBody:       "TOO MANY REQUESTS",
+			wantRetryAfter: "2",
+		},
+		{
+			name:           "retry after at least one second",
+			limiter:        mockLimiter{limited: true},
+			wantCode:       http.StatusTooManyRequests,
+			wantBody:       "TOO MANY REQUESTS",
+			wantRetryAfter: "1",
+		},
+		{
+			name:     "limiter error",
+			limiter:  mockLimiter{err: errors.New("mock error")},
+			wantCode: http.StatusInternalServerError,
+			wantBody: "INTERNAL SERVER ERROR",
+			wantErr:  errors.New("mock error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logErr error
+			builder := NewMiddlewareBuilder(tc.limiter).LogFunc(func(ctx *web.Context, err error) {
+				logErr = err
+			})
+			h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+			h.Get("/user", func(ctx *web.Context) {
+				ctx.RespString(http.StatusOK, "hello, user")
+			})
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user", nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantRetryAfter, recorder.Header().Get("Retry-After"))
+			assert.Equal(t, tc.wantErr, logErr)
+		})
+	}
+}
+
+func TestMiddlewareBuilder_KeyFunc(t *testing.T) {
+	testCases := []struct {
+		name    string
+		keyFunc KeyFunc
+		// 两个请求，第二个请求会不会被限流
+		req1 func() *http.Request
+		req2 func() *http.Request
+
+		wantCode int
+	}{
+		{
+			name:    "same ip",
+			keyFunc: KeyByIP(),
+			req1: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				return req
+			},
+			req2: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+				req.RemoteAddr = "10.0.0.1:5678"
+				return req
+			},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:    "different ip",
+			keyFunc: KeyByIP(),
+			req1: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				return req
+			},
+			req2: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+				req.RemoteAddr = "10.0.0.2:1234"
+				return req
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:    "same route",
+			keyFunc: KeyByRoute(),
+			req1: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				return req
+			},
+			req2: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/2", nil)
+				req.RemoteAddr = "10.0.0.2:1234"
+				return req
+			},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:    "different route",
+			keyFunc: KeyByRoute(),
+			req1: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/user/1", nil)
+			},
+			req2: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/order/1", nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:    "same header",
+			keyFunc: KeyByHeader("X-API-Key"),
+			req1: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+				req.Header.Set("X-API-Key", "abc")
+				return req
+			},
+			req2: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+				req.Header.Set("X-API-Key", "abc")
+				return req
+			},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:    "different header",
+			keyFunc: KeyByHeader("X-API-Key"),
+			req1: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+				req.Header.Set("X-API-Key", "abc")
+				return req
+			},
+			req2: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+				req.Header.Set("X-API-Key", "def")
+				return req
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// 每个 key 一分钟只能有一个请求
+			limiter := ratelimit.NewFixWindowLimiter(time.Minute, 1)
+			mdl := NewMiddlewareBuilder(limiter).KeyFunc(tc.keyFunc).Build()
+			h := web.NewHTTPServer()
+			handler := func(ctx *web.Context) {
+				ctx.RespString(http.StatusOK, "ok")
+			}
+			// 注册在路由上，这样 KeyByRoute 能拿到命中的路由
+			h.Get("/user/:id", handler, mdl)
+			h.Get("/order/:id", handler, mdl)
+
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, tc.req1())
+			assert.Equal(t, http.StatusOK, recorder.Code)
+
+			recorder = httptest.NewRecorder()
+			h.ServeHTTP(recorder, tc.req2())
+			assert.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
+
+type mockLimiter struct {
+	limited    bool
+	retryAfter time.Duration
+	err        error
+}
+
+func (m mockLimiter) Limit(ctx context.Context, key string) (bool, time.Duration, error) {
+	return m.limited, m.retryAfter, m.err
+}
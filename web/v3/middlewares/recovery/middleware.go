@@ -0,0 +1,50 @@
+package recovery
+
+import (
+	web "leanring-go/web/v3"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// MiddlewareBuilder 从 panic 里面恢复过来，并且返回 StatusCode 和 Data
+// 和 errhdl 一起使用的时候，recovery 要注册在 errhdl 后面，也就是包在 errhdl 里面，
+// 例如 ServerWithMiddleware(errhdl, recovery)，这样 errhdl 才能看到 500
+type MiddlewareBuilder struct {
+	StatusCode int
+	Data       []byte
+	// LogFunc 记录 panic 的信息，stack 是 panic 时候的调用栈
+	LogFunc func(ctx *web.Context, err any, stack []byte)
+}
+
+func NewMiddlewareBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		StatusCode: http.StatusInternalServerError,
+		Data:       []byte("INTERNAL SERVER ERROR"),
+		LogFunc: func(ctx *web.Context, err any, stack []byte) {
+			log.Printf("web: 处理 %s %s 的时候发生了 panic: %v\n%s",
+				ctx.Req.Method, ctx.Req.URL.Path, err, stack)
+		},
+	}
+}
+
+func (m *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				// http.ErrAbortHandler 是用来中断请求的，交给 net/http 处理
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				m.LogFunc(ctx, err, debug.Stack())
+				ctx.RespStatusCode = m.StatusCode
+				ctx.RespData = m.Data
+			}()
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package recovery
+
+import (
+	"github.com/stretchr/testify/assert"
+	web "leanring-go/web/v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	var logErr any
+	var logStack []byte
+	builder := NewMiddlewareBuilder()
+	builder.Data = []byte("服务器出错了")
+	builder.LogFunc = func(ctx *web.Context, err any, stack []byte) {
+		logErr = err
+		logStack = stack
+	}
+	h := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	h.Get("/panic", func(ctx *web.Context) {
+		panic("出错了")
+	})
+	h.Get("/user", func(ctx *web.Context) {
+		ctx.RespString(http.StatusOK, "hello, user")
+	})
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "服务器出错了", recorder.Body.String())
+	assert.Equal(t, "出错了", logErr)
+	assert.Contains(t, string(logStack), "panic")
+
+	// 没有 panic 的时候不影响响应
+	logErr = nil
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "hello, user", recorder.Body.String())
+	assert.Nil(t, logErr)
+
+	h.Get("/abort", func(ctx *web.Context) {
+		panic(http.ErrAbortHandler)
+	})
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+}
@@ -0,0 +1,318 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIInfo 文档的基本信息
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPI 根据注册的路由生成 OpenAPI 3 的 JSON 文档
+// 请求体和响应体的结构来自于 RouteMeta 里面的类型，都当作 JSON 处理
+// 只包含默认路由树里面的路由，不同域名下的同一个路径在文档里面没办法区分
+// 同一个方法下，只有参数的正则表达式不一样的路由，例如 /user/:id 和 /user/:id(^[0-9]+$)，
+// 在文档里面是同一个路径，这种情况返回 error
+func (h *HTTPServer) OpenAPI(info OpenAPIInfo) ([]byte, error) {
+	doc := &openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]map[string]*openAPIOperation),
+	}
+	b := &schemaBuilder{
+		components: make(map[string]*openAPISchema),
+		names:      make(map[reflect.Type]string),
+	}
+	for _, route := range h.Routes() {
+		if route.Host != "" {
+			continue
+		}
+		path, params := openAPIPath(route.Pattern)
+		op := &openAPIOperation{
+			OperationID: route.Method + " " + route.Pattern,
+			Summary:     route.Meta.Summary,
+			Description: route.Meta.Description,
+			Tags:        route.Meta.Tags,
+			Parameters:  params,
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "OK"},
+			},
+		}
+		if route.Meta.Request != nil {
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content:  jsonContent(b.schema(reflect.TypeOf(route.Meta.Request))),
+			}
+		}
+		if route.Meta.Response != nil {
+			op.Responses["200"].Content = jsonContent(b.schema(reflect.TypeOf(route.Meta.Response)))
+		}
+		ops, ok := doc.Paths[path]
+		if !ok {
+			ops = make(map[string]*openAPIOperation, 2)
+			doc.Paths[path] = ops
+		}
+		method := strings.ToLower(route.Method)
+		if old, ok := ops[method]; ok {
+			return nil, fmt.Errorf("web: 路由 %s 和 %s 对应同一个 OpenAPI 路径 %s", old.OperationID, op.OperationID, path)
+		}
+		ops[method] = op
+	}
+	if len(b.components) > 0 {
+		doc.Components = &openAPIComponents{Schemas: b.components}
+	}
+	return json.Marshal(doc)
+}
+
+// OpenAPIHandler 返回 OpenAPI 文档
+// 每次请求都会重新生成，所以之后注册的路由也能看到
+func (h *HTTPServer) OpenAPIHandler(info OpenAPIInfo) HandleFunc {
+	return func(ctx *Context) {
+		data, err := h.OpenAPI(info)
+		if err != nil {
+			ctx.RespStatusCode = http.StatusInternalServerError
+			ctx.RespData = []byte("INTERNAL SERVER ERROR")
+			return
+		}
+		ctx.Resp.Header().Set("Content-Type", "application/json")
+		ctx.RespStatusCode = http.StatusOK
+		ctx.RespData = data
+	}
+}
+
+// openAPIPath 把 /order/:id(^[0-9]+$)/*filepath 转换成 /order/{id}/{filepath}
+// 同时返回路径参数
+func openAPIPath(pattern string) (string, []*openAPIParameter) {
+	if pattern == "/" {
+		return pattern, nil
+	}
+	segs := strings.Split(pattern[1:], "/")
+	var params []*openAPIParameter
+	wildcards := 0
+	for i, seg := range segs {
+		var param *openAPIParameter
+		switch seg[0] {
+		case ':':
+			name, expr, _ := parseParam(seg)
+			param = &openAPIParameter{
+				Name:   name,
+				Schema: &openAPISchema{Type: "string", Pattern: expr},
+			}
+		case '*':
+			name := seg[1:]
+			if name == "" {
+				// 匿名的通配符没有名字，只能生成一个
+				wildcards++
+				name = "wildcard"
+				if wildcards > 1 {
+					name += strconv.Itoa(wildcards)
+				}
+			}
+			param = &openAPIParameter{
+				Name:   name,
+				Schema: &openAPISchema{Type: "string"},
+			}
+		default:
+			continue
+		}
+		param.In = "path"
+		param.Required = true
+		params = append(params, param)
+		segs[i] = "{" + param.Name + "}"
+	}
+	return "/" + strings.Join(segs, "/"), params
+}
+
+func jsonContent(schema *openAPISchema) map[string]*openAPIMediaType {
+	return map[string]*openAPIMediaType{
+		"application/json": {Schema: schema},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaBuilder 根据 Go 的类型生成 JSON Schema
+// 具名的结构体放到 components 里面，通过 $ref 引用，这样也能处理循环引用
+type schemaBuilder struct {
+	components map[string]*openAPISchema
+	// 具名的结构体在 components 里面的名字
+	names map[reflect.Type]string
+}
+
+func (b *schemaBuilder) schema(typ reflect.Type) *openAPISchema {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == timeType {
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// []byte 在 JSON 里面是 base64 编码的字符串
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: b.schema(typ.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: b.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return b.structSchema(typ)
+		}
+		name, ok := b.names[typ]
+		if !ok {
+			name = b.componentName(typ)
+			b.names[typ] = name
+			// 先占位，避免循环引用导致死循环
+			placeholder := &openAPISchema{}
+			b.components[name] = placeholder
+			*placeholder = *b.structSchema(typ)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	default:
+		// interface 之类的类型，任意值都可以
+		return &openAPISchema{}
+	}
+}
+
+// componentName 给具名的结构体起一个 components 里面不重复的名字
+// 一般就是类型名，和别的包里面的同名类型冲突的时候加上包路径
+func (b *schemaBuilder) componentName(typ reflect.Type) string {
+	name := componentKey(typ.Name())
+	if _, ok := b.components[name]; !ok {
+		return name
+	}
+	name = componentKey(typ.PkgPath() + "." + typ.Name())
+	res := name
+	for i := 2; ; i++ {
+		if _, ok := b.components[res]; !ok {
+			return res
+		}
+		res = name + strconv.Itoa(i)
+	}
+}
+
+// componentKey components 的名字只能包含字母、数字、.、- 和 _
+// 泛型类型的名字例如 Page[leanring-go/web/v3.User]，其余的字符都替换成 _
+func componentKey(name string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name), "_")
+}
+
+func (b *schemaBuilder) structSchema(typ reflect.Type) *openAPISchema {
+	res := &openAPISchema{
+		Type:       "object",
+		Properties: make(map[string]*openAPISchema, typ.NumField()),
+	}
+	b.fillProperties(res, typ)
+	return res
+}
+
+// fillProperties 按照 encoding/json 的规则处理字段
+func (b *schemaBuilder) fillProperties(res *openAPISchema, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+		tag := fd.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fdType := fd.Type
+		for fdType.Kind() == reflect.Pointer {
+			fdType = fdType.Elem()
+		}
+		// 没有指定名字的组合结构体，字段会被展开
+		if fd.Anonymous && name == "" && fdType.Kind() == reflect.Struct {
+			b.fillProperties(res, fdType)
+			continue
+		}
+		if !fd.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = fd.Name
+		}
+		res.Properties[name] = b.schema(fd.Type)
+	}
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components *openAPIComponents                      `json:"components,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"leanring-go/micro/proto/gen"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type openAPIUser struct {
+	ID       int64     `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Tags     []string  `json:"tags"`
+	Avatar   []byte    `json:"avatar"`
+	Ctime    time.Time `json:"ctime"`
+	Password string    `json:"-"`
+	password string
+	// 循环引用
+	Friends []*openAPIUser `json:"friends"`
+}
+
+type openAPICreateUserReq struct {
+	openAPIBaseReq
+	Name  string            `json:"name"`
+	Extra map[string]string `json:"extra"`
+	Age   *int
+}
+
+type openAPIBaseReq struct {
+	RequestID string `json:"request_id"`
+}
+
+func TestHTTPServer_OpenAPI(t *testing.T) {
+	h := NewHTTPServer()
+	mockHandler := func(ctx *Context) {}
+	h.Get("/user/:id(^[0-9]+$)", mockHandler).Meta(RouteMeta{
+		Summary:  "查询用户",
+		Tags:     []string{"user"},
+		Response: openAPIUser{},
+	})
+	h.POST("/user", mockHandler).Meta(RouteMeta{
+		Request:  &openAPICreateUserReq{},
+		Response: &openAPIUser{},
+	})
+	h.Get("/static/*/*filepath", mockHandler)
+	h.Get("/openapi.json", h.OpenAPIHandler(OpenAPIInfo{Title: "user", Version: "v1"}))
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+  "openapi": "3.0.3",
+  "info": {"title": "user", "version": "v1"},
+  "paths": {
+    "/openapi.json": {
+      "get": {
+        "operationId": "GET /openapi.json",
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/static/{wildcard}/{filepath}": {
+      "get": {
+        "operationId": "GET /static/*/*filepath",
+        "parameters": [
+          {"name": "wildcard", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "filepath", "in": "path", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/user": {
+      "post": {
+        "operationId": "POST /user",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/openAPICreateUserReq"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/openAPIUser"}}}
+          }
+        }
+      }
+    },
+    "/user/{id}": {
+      "get": {
+        "operationId": "GET /user/:id(^[0-9]+$)",
+        "summary": "查询用户",
+        "tags": ["user"],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string", "pattern": "^[0-9]+$"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/openAPIUser"}}}
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "openAPICreateUserReq": {
+        "type": "object",
+        "properties": {
+          "request_id": {"type": "string"},
+          "name": {"type": "string"},
+          "extra": {"type": "object", "additionalProperties": {"type": "string"}},
+          "Age": {"type": "integer", "format": "int32"}
+        }
+      },
+      "openAPIUser": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "name": {"type": "string"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "avatar": {"type": "string", "format": "byte"},
+          "ctime": {"type": "string", "format": "date-time"},
+          "friends": {"type": "array", "items": {"$ref": "#/components/schemas/openAPIUser"}}
+        }
+      }
+    }
+  }
+}`, recorder.Body.String())
+}
+
+type openAPIPage[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+}
+
+func TestHTTPServer_OpenAPIComponentName(t *testing.T) {
+	// 和 gen.User 同名，但是在不同的包里面
+	type User struct {
+		Name string `json:"name"`
+	}
+	h := NewHTTPServer()
+	mockHandler := func(ctx *Context) {}
+	h.Get("/a", mockHandler).Meta(RouteMeta{Response: User{}})
+	h.Get("/b", mockHandler).Meta(RouteMeta{Response: gen.User{}})
+	h.Get("/c", mockHandler).Meta(RouteMeta{Response: openAPIPage[openAPIBaseReq]{}})
+	h.Get("/d", mockHandler).Meta(RouteMeta{Response: []User{}})
+
+	data, err := h.OpenAPI(OpenAPIInfo{Title: "user", Version: "v1"})
+	require.NoError(t, err)
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema openAPISchema `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	ref := func(path string) string {
+		return doc.Paths[path]["get"].Responses["200"].Content["application/json"].Schema.Ref
+	}
+	assert.Equal(t, "#/components/schemas/User", ref("/a"))
+	assert.Equal(t, "#/components/schemas/leanring-go_micro_proto_gen.User", ref("/b"))
+	assert.Equal(t, "#/components/schemas/openAPIPage_leanring-go_web_v3.openAPIBaseReq", ref("/c"))
+	// 同一个类型使用同一个名字
+	assert.Equal(t, "#/components/schemas/User",
+		doc.Paths["/d"]["get"].Responses["200"].Content["application/json"].Schema.Items.Ref)
+	assert.Len(t, doc.Components.Schemas, 4)
+}
+
+func TestHTTPServer_OpenAPIPathConflict(t *testing.T) {
+	h := NewHTTPServer()
+	mockHandler := func(ctx *Context) {}
+	h.Get("/user/:id(^[0-9]+$)", mockHandler)
+	h.Get("/user/:id", mockHandler)
+	// 方法不一样的话不冲突
+	h.POST("/order/:id(^[0-9]+$)", mockHandler)
+	h.Get("/order/:id", mockHandler)
+	_, err := h.OpenAPI(OpenAPIInfo{Title: "user", Version: "v1"})
+	assert.Equal(t, errors.New("web: 路由 GET /user/:id 和 GET /user/:id(^[0-9]+$) 对应同一个 OpenAPI 路径 /user/{id}"), err)
+}
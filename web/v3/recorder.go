@@ -0,0 +1,89 @@
+package web
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder 记录直接写入 http.ResponseWriter 的响应码和字节数
+// 例如 StaticResourceHandler 和 FileDownloader 通过 http.ServeContent 返回的 304、206 和 416
+// Middleware 用它替换 Context.Resp，返回之前换回来：
+//
+//	resp := ctx.Resp
+//	recorder := web.NewResponseRecorder(resp)
+//	ctx.Resp = recorder
+//	defer func() { ctx.Resp = resp }()
+//
+// 它实现了 http.Flusher 和 http.Hijacker，这样流式响应和 WebSocket 之类的协议升级不受影响
+type ResponseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	size       int
+}
+
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w}
+}
+
+// StatusCode 返回给客户端的响应码
+// Context.RespStatusCode 优先，其次是直接写入的响应码，都没有的话 net/http 默认就是 200
+func (r *ResponseRecorder) StatusCode(ctx *Context) int {
+	if ctx.RespStatusCode > 0 {
+		return ctx.RespStatusCode
+	}
+	if r.statusCode > 0 {
+		return r.statusCode
+	}
+	return http.StatusOK
+}
+
+// Size 响应体的字节数，包括 Context.RespData 和直接写入的数据
+func (r *ResponseRecorder) Size(ctx *Context) int {
+	return len(ctx.RespData) + r.size
+}
+
+func (r *ResponseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *ResponseRecorder) Write(data []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.size += n
+	return n, err
+}
+
+// Flush 底层的 http.ResponseWriter 不支持的时候什么也不做
+func (r *ResponseRecorder) Flush() {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("web: http.ResponseWriter 不支持 Hijack")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && r.statusCode == 0 {
+		// 接管连接之后的响应不经过 http.ResponseWriter，一般是协议升级
+		r.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap 给 http.ResponseController 使用
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler HandleFunc
+
+		wantCode int
+		wantSize int
+	}{
+		{
+			name: "resp status code",
+			handler: func(ctx *Context) {
+				ctx.RespString(http.StatusCreated, "created")
+			},
+			wantCode: http.StatusCreated,
+			wantSize: len("created"),
+		},
+		{
+			name: "resp data only",
+			handler: func(ctx *Context) {
+				ctx.RespData = []byte("data")
+			},
+			wantCode: http.StatusOK,
+			wantSize: len("data"),
+		},
+		{
+			name: "write header",
+			handler: func(ctx *Context) {
+				ctx.Resp.WriteHeader(http.StatusPartialContent)
+				_, _ = ctx.Resp.Write([]byte("part"))
+			},
+			wantCode: http.StatusPartialContent,
+			wantSize: len("part"),
+		},
+		{
+			name: "write",
+			handler: func(ctx *Context) {
+				_, _ = ctx.Resp.Write([]byte("raw"))
+			},
+			wantCode: http.StatusOK,
+			wantSize: len("raw"),
+		},
+		{
+			name:     "nothing",
+			handler:  func(ctx *Context) {},
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var code, size int
+			mdl := func(next HandleFunc) HandleFunc {
+				return func(ctx *Context) {
+					resp := ctx.Resp
+					recorder := NewResponseRecorder(resp)
+					ctx.Resp = recorder
+					next(ctx)
+					ctx.Resp = resp
+					code, size = recorder.StatusCode(ctx), recorder.Size(ctx)
+				}
+			}
+			h := NewHTTPServer(ServerWithMiddleware(mdl))
+			h.Get("/", tc.handler)
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tc.wantCode, code)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantSize, size)
+		})
+	}
+
+	// 底层的 http.ResponseWriter 不支持的时候返回 error
+	_, _, err := NewResponseRecorder(httptest.NewRecorder()).Hijack()
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Route 注册好的路由，用于补充路由的名字和元数据
+// h.Get("/user/:id", getUser).Name("user").Meta(RouteMeta{Summary: "查询用户"})
+type Route struct {
+	r *router
+	n *node
+}
+
+// Name 设置路由的名字，之后可以通过 HTTPServer.URL 反向生成 URL
+// 名字不能重复
+func (r *Route) Name(name string) *Route {
+	if name == "" {
+		panic("web: 路由名字不能为空字符串")
+	}
+	if _, ok := r.r.names[name]; ok {
+		panic(fmt.Sprintf("web: 路由名字冲突，重复注册[%s]", name))
+	}
+	if r.n.name != "" {
+		panic(fmt.Sprintf("web: 路由 %s 已经有名字 %s", r.n.route, r.n.name))
+	}
+	r.n.name = name
+	r.r.names[name] = r.n
+	return r
+}
+
+// Meta 设置路由的元数据
+func (r *Route) Meta(meta RouteMeta) *Route {
+	r.n.meta = meta
+	return r
+}
+
+// RouteMeta 路由的元数据，用于生成 OpenAPI 文档
+type RouteMeta struct {
+	Summary     string
+	Description string
+	Tags        []string
+	// Request 请求体的类型，传入零值就可以，例如 CreateUserReq{}
+	Request any
+	// Response 响应体的类型，传入零值就可以，例如 User{}
+	Response any
+}
+
+// RouteInfo 路由的信息
+type RouteInfo struct {
+	// Host 路由所属的域名，例如 api.example.com 或者 *.example.com
+	// 默认的路由树为空字符串
+	Host   string
+	Method string
+	// Pattern 注册时的完整路径，例如 /order/detail/:id
+	Pattern string
+	// Name 路由的名字，没有设置的时候为空字符串
+	Name string
+	// HandlerName handler 的函数名
+	HandlerName string
+	Meta        RouteMeta
+}
+
+// Routes 返回所有注册了 handler 的路由，包括各个域名下的路由
+// 按照 Host 排序，Host 相同的按照 Pattern 排序，Pattern 相同的按照 Method 排序
+func (r *router) Routes() []RouteInfo {
+	res := routesOf("", r.trees, nil)
+	for host, trees := range r.hosts {
+		res = routesOf(host, trees, res)
+	}
+	for suffix, trees := range r.wildcardHosts {
+		res = routesOf("*"+suffix, trees, res)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Host != res[j].Host {
+			return res[i].Host < res[j].Host
+		}
+		if res[i].Pattern != res[j].Pattern {
+			return res[i].Pattern < res[j].Pattern
+		}
+		return res[i].Method < res[j].Method
+	})
+	return res
+}
+
+func routesOf(host string, trees map[string]*node, res []RouteInfo) []RouteInfo {
+	for method, root := range trees {
+		root.walk(func(n *node) {
+			if n.handler == nil {
+				return
+			}
+			res = append(res, RouteInfo{
+				Host:        host,
+				Method:      method,
+				Pattern:     n.route,
+				Name:        n.name,
+				HandlerName: handlerName(n.handler),
+				Meta:        n.meta,
+			})
+		})
+	}
+	return res
+}
+
+// walk 深度优先遍历 n 和它所有的子节点
+func (n *node) walk(fn func(n *node)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+	for _, child := range []*node{n.regChild, n.paramChild, n.startChild} {
+		if child != nil {
+			child.walk(fn)
+		}
+	}
+}
+
+func handlerName(handler HandleFunc) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(handler).Pointer())
+	if fn == nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+// URL 根据路由的名字生成路径
+// params 里面是路径参数，例如 /user/:id 需要 id，/static/*filepath 需要 filepath，
+// 匿名的通配符 * 使用 * 作为 key
+// 正则路由的参数需要能够匹配正则表达式，只有末尾的通配符的值可以包含 /
+// 参数和通配符的每一段都不能是 . 或者 ..，否则客户端规范化路径之后就不再是这个路由了
+func (r *router) URL(name string, params map[string]string) (string, error) {
+	n, ok := r.names[name]
+	if !ok {
+		return "", fmt.Errorf("web: 找不到名字为 %s 的路由", name)
+	}
+	if n.route == "/" {
+		return "/", nil
+	}
+	segs := strings.Split(n.route[1:], "/")
+	// 下一个正则路径参数在 n.regExprs 里面的下标
+	regIdx := 0
+	var sb strings.Builder
+	for i, seg := range segs {
+		sb.WriteByte('/')
+		switch seg[0] {
+		case ':':
+			paramName, expr, isReg := parseParam(seg)
+			val, err := urlParam(name, paramName, params)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(val, "/") {
+				return "", fmt.Errorf("web: 路由 %s 的参数 %s 不能包含 /", name, paramName)
+			}
+			if err = checkDotSegment(name, paramName, val); err != nil {
+				return "", err
+			}
+			if isReg {
+				// 使用注册的时候编译好的正则表达式
+				if !n.regExprs[regIdx].MatchString(val) {
+					return "", fmt.Errorf("web: 路由 %s 的参数 %s 不匹配 %s", name, paramName, expr)
+				}
+				regIdx++
+			}
+			sb.WriteString(url.PathEscape(val))
+		case '*':
+			paramName := seg[1:]
+			if paramName == "" {
+				paramName = "*"
+			}
+			val, err := urlParam(name, paramName, params)
+			if err != nil {
+				return "", err
+			}
+			pieces := strings.Split(strings.Trim(val, "/"), "/")
+			if i != len(segs)-1 && len(pieces) > 1 {
+				return "", fmt.Errorf("web: 路由 %s 的参数 %s 不能包含 /", name, paramName)
+			}
+			for j, piece := range pieces {
+				if piece == "" {
+					return "", fmt.Errorf("web: 路由 %s 的参数 %s 不能有连续的 /", name, paramName)
+				}
+				if err = checkDotSegment(name, paramName, piece); err != nil {
+					return "", err
+				}
+				if j > 0 {
+					sb.WriteByte('/')
+				}
+				sb.WriteString(url.PathEscape(piece))
+			}
+		default:
+			sb.WriteString(seg)
+		}
+	}
+	return sb.String(), nil
+}
+
+// checkDotSegment . 和 .. 会被客户端当成当前目录和上级目录
+func checkDotSegment(name string, paramName string, val string) error {
+	if val == "." || val == ".." {
+		return fmt.Errorf("web: 路由 %s 的参数 %s 不能是 . 或者 ..", name, paramName)
+	}
+	return nil
+}
+
+func urlParam(name string, paramName string, params map[string]string) (string, error) {
+	val, ok := params[paramName]
+	if !ok {
+		return "", fmt.Errorf("web: 路由 %s 缺少参数 %s", name, paramName)
+	}
+	if val == "" {
+		return "", fmt.Errorf("web: 路由 %s 的参数 %s 不能为空字符串", name, paramName)
+	}
+	return val, nil
+}
@@ -0,0 +1,204 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func getUser(ctx *Context) {}
+
+func TestHTTPServer_Routes(t *testing.T) {
+	h := NewHTTPServer()
+	mockHandler := func(ctx *Context) {}
+	h.Get("/", mockHandler)
+	h.Get("/user/:id", getUser).Name("user").Meta(RouteMeta{Summary: "查询用户"})
+	h.DELETE("/user/:id", mockHandler)
+	h.Get("/static/*filepath", mockHandler)
+	h.POST("/order/:id(^[0-9]+$)", mockHandler)
+	h.Group("/api").PUT("/user", mockHandler).Meta(RouteMeta{Tags: []string{"user"}})
+	// 只有 Middleware 的节点不是路由
+	h.UseRoute(http.MethodGet, "/order", func(next HandleFunc) HandleFunc {
+		return next
+	})
+
+	routes := h.Routes()
+	var patterns []string
+	for _, route := range routes {
+		patterns = append(patterns, route.Method+" "+route.Pattern)
+	}
+	assert.Equal(t, []string{
+		"GET /",
+		"PUT /api/user",
+		"POST /order/:id(^[0-9]+$)",
+		"GET /static/*filepath",
+		"DELETE /user/:id",
+		"GET /user/:id",
+	}, patterns)
+
+	assert.Equal(t, "leanring-go/web/v3.getUser", routes[5].HandlerName)
+	assert.Equal(t, RouteMeta{Summary: "查询用户"}, routes[5].Meta)
+	assert.Equal(t, "user", routes[5].Name)
+	assert.Equal(t, RouteMeta{Tags: []string{"user"}}, routes[1].Meta)
+}
+
+func TestHTTPServer_URL(t *testing.T) {
+	h := NewHTTPServer()
+	mockHandler := func(ctx *Context) {}
+	h.Get("/", mockHandler).Name("home")
+	h.Get("/user/:id", mockHandler).Name("user")
+	h.Get("/order/:id(^[0-9]+$)/detail", mockHandler).Name("order")
+	h.Get("/static/*filepath", mockHandler).Name("static")
+	h.Get("/proxy/*/api", mockHandler).Name("proxy")
+	h.Group("/api").PUT("/user/:id", mockHandler).Name("api_user")
+	h.Get("/shop/:shop(^[a-z]+$)/item/:item(^[0-9]+$)", mockHandler).Name("item")
+
+	testCases := []struct {
+		name      string
+		routeName string
+		params    map[string]string
+		wantURL   string
+		wantErr   error
+	}{
+		{
+			name:      "root",
+			routeName: "home",
+			wantURL:   "/",
+		},
+		{
+			name:      "param",
+			routeName: "user",
+			params:    map[string]string{"id": "123"},
+			wantURL:   "/user/123",
+		},
+		{
+			// 参数需要转义
+			name:      "escape param",
+			routeName: "user",
+			params:    map[string]string{"id": "a b?"},
+			wantURL:   "/user/a%20b%3F",
+		},
+		{
+			name:      "group",
+			routeName: "api_user",
+			params:    map[string]string{"id": "123"},
+			wantURL:   "/api/user/123",
+		},
+		{
+			name:      "regexp",
+			routeName: "order",
+			params:    map[string]string{"id": "123"},
+			wantURL:   "/order/123/detail",
+		},
+		{
+			name:      "regexp mismatch",
+			routeName: "order",
+			params:    map[string]string{"id": "abc"},
+			wantErr:   errors.New("web: 路由 order 的参数 id 不匹配 ^[0-9]+$"),
+		},
+		{
+			// 末尾的通配符可以包含 /
+			name:      "wildcard",
+			routeName: "static",
+			params:    map[string]string{"filepath": "js/app.js"},
+			wantURL:   "/static/js/app.js",
+		},
+		{
+			name:      "anonymous wildcard",
+			routeName: "proxy",
+			params:    map[string]string{"*": "user"},
+			wantURL:   "/proxy/user/api",
+		},
+		{
+			// 中间的通配符只能匹配一段
+			name:      "middle wildcard with slash",
+			routeName: "proxy",
+			params:    map[string]string{"*": "user/order"},
+			wantErr:   errors.New("web: 路由 proxy 的参数 * 不能包含 /"),
+		},
+		{
+			name:      "wildcard with empty segment",
+			routeName: "static",
+			params:    map[string]string{"filepath": "js//app.js"},
+			wantErr:   errors.New("web: 路由 static 的参数 filepath 不能有连续的 /"),
+		},
+		{
+			name:      "param with slash",
+			routeName: "user",
+			params:    map[string]string{"id": "1/2"},
+			wantErr:   errors.New("web: 路由 user 的参数 id 不能包含 /"),
+		},
+		{
+			name:      "dot dot param",
+			routeName: "user",
+			params:    map[string]string{"id": ".."},
+			wantErr:   errors.New("web: 路由 user 的参数 id 不能是 . 或者 .."),
+		},
+		{
+			// 客户端会把 /static/../x 规范化成 /x
+			name:      "dot dot wildcard",
+			routeName: "static",
+			params:    map[string]string{"filepath": "../x"},
+			wantErr:   errors.New("web: 路由 static 的参数 filepath 不能是 . 或者 .."),
+		},
+		{
+			name:      "dot wildcard",
+			routeName: "static",
+			params:    map[string]string{"filepath": "js/./app.js"},
+			wantErr:   errors.New("web: 路由 static 的参数 filepath 不能是 . 或者 .."),
+		},
+		{
+			name:      "dots in file name",
+			routeName: "static",
+			params:    map[string]string{"filepath": "js/app..min.js"},
+			wantURL:   "/static/js/app..min.js",
+		},
+		{
+			name:      "multiple regexp",
+			routeName: "item",
+			params:    map[string]string{"shop": "abc", "item": "123"},
+			wantURL:   "/shop/abc/item/123",
+		},
+		{
+			// 第二个参数使用第二个正则表达式
+			name:      "multiple regexp mismatch",
+			routeName: "item",
+			params:    map[string]string{"shop": "abc", "item": "abc"},
+			wantErr:   errors.New("web: 路由 item 的参数 item 不匹配 ^[0-9]+$"),
+		},
+		{
+			name:      "missing param",
+			routeName: "user",
+			wantErr:   errors.New("web: 路由 user 缺少参数 id"),
+		},
+		{
+			name:      "empty param",
+			routeName: "user",
+			params:    map[string]string{"id": ""},
+			wantErr:   errors.New("web: 路由 user 的参数 id 不能为空字符串"),
+		},
+		{
+			name:      "unknown name",
+			routeName: "abc",
+			wantErr:   errors.New("web: 找不到名字为 abc 的路由"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := h.URL(tc.routeName, tc.params)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantURL, u)
+		})
+	}
+
+	assert.PanicsWithValue(t, "web: 路由名字冲突，重复注册[user]", func() {
+		h.POST("/user", mockHandler).Name("user")
+	})
+	assert.PanicsWithValue(t, "web: 路由名字不能为空字符串", func() {
+		h.POST("/order", mockHandler).Name("")
+	})
+}
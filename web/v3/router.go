@@ -2,6 +2,9 @@ package web
 
 import (
 	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -10,19 +13,62 @@ import (
 type router struct {
 	// http method => 路由树根节点
 	trees map[string]*node
+
+	// 路由名字 => 路由节点，用于反向生成 URL
+	// 所有域名共用
+	names map[string]*node
+
+	// 域名 => 这个域名自己的路由树，key 是小写的完整域名，例如 api.example.com
+	hosts map[string]map[string]*node
+	// 通配符域名 => 路由树，key 是去掉 * 之后的后缀，例如 .example.com
+	wildcardHosts map[string]map[string]*node
 }
 
 // newRouter 初始化 router
 func newRouter() router {
 	return router{
-		trees: map[string]*node{},
+		trees:         map[string]*node{},
+		names:         map[string]*node{},
+		hosts:         map[string]map[string]*node{},
+		wildcardHosts: map[string]map[string]*node{},
 	}
 }
 
 // AddRoute 注册路由
 // method 是方法
 // path 必须以 / 开始并且结尾不能有 /，中间也不允许有连续的 /
-func (r *router) addRoute(method string, path string, handlerFunc HandleFunc) {
+// mdls 是注册在这个路由上的 Middleware
+func (r *router) addRoute(method string, path string, handlerFunc HandleFunc, mdls ...Middleware) *node {
+	return r.addHostRoute("", method, path, handlerFunc, mdls...)
+}
+
+// addHostRoute 在域名 host 下面注册路由，host 为空字符串的时候注册到默认的路由树
+func (r *router) addHostRoute(host string, method string, path string, handlerFunc HandleFunc, mdls ...Middleware) *node {
+	root, regExprs := nodeOrCreate(r.treesOrCreate(host), method, path)
+	if root.handler != nil {
+		// 根节点特殊处理
+		if path == "/" {
+			panic("web: 路由冲突，重复注册[/]")
+		}
+		panic(fmt.Sprintf("web: 路由冲突， 重复注册[%s]", path))
+	}
+	root.handler = handlerFunc
+	root.routeMdls = mdls
+	root.route = path
+	root.regExprs = regExprs
+	return root
+}
+
+// addMiddlewares 在路由上注册 Middleware，不要求这个路由有 handler
+// 同一个路由可以多次注册，按照注册顺序执行
+func (r *router) addMiddlewares(method string, path string, mdls ...Middleware) {
+	root, _ := nodeOrCreate(r.trees, method, path)
+	root.mdls = append(root.mdls, mdls...)
+}
+
+// nodeOrCreate 校验 path，并且在 trees 里面找到 path 对应的节点，没有就创建
+// 同时返回沿途正则路径参数的正则表达式，按照在 path 里面出现的顺序排列
+func nodeOrCreate(trees map[string]*node, method string, path string) (*node, []*regexp.Regexp) {
 	// 这里注册到路由树里面
 	// 开头不能没有 /
 	if path == "" {
@@ -39,150 +85,481 @@ func (r *router) addRoute(method string, path string, handlerFunc HandleFunc) {
 
 	// 中间连续 //，可以用 strings.contains("//")检查
 
-	root, ok := r.trees[method]
+	root, ok := trees[method]
 	if !ok {
 		//	说明没有根节点，需要创建根节点
 		root = &node{
 			path: "/",
 		}
-		r.trees[method] = root
+		trees[method] = root
 	}
 	// 根节点特殊处理
 	if path == "/" {
-		// 跟节点重复注册
-		if root.handler != nil {
-			panic("web: 路由冲突，重复注册[/]")
-		}
-		root.handler = handlerFunc
-		return
+		return root, nil
 	}
+	var regExprs []*regexp.Regexp
 	//	切割 path，去掉前缀“/”：path[1:]
-	for _, seg := range strings.Split(path[1:], "/") {
+	// 连续的静态段合并在一起插入，这样才能压缩成一个节点
+	static := ""
+	for i, seg := range strings.Split(path[1:], "/") {
 		if seg == "" {
 			panic("web: 路由不能有连续的 x")
 		}
-		child := root.childrenOrCreate(seg)
-		root = child
-	}
-	if root.handler != nil {
-		panic(fmt.Sprintf("web: 路由冲突， 重复注册[%s]", path))
+		if i > 0 {
+			static += "/"
+		}
+		if seg[0] != ':' && seg[0] != '*' {
+			static += seg
+			continue
+		}
+		root = root.staticChildOrCreate(static)
+		static = ""
+		root = root.childrenOrCreate(seg)
+		if root.typ == nodeTypeReg {
+			regExprs = append(regExprs, root.regExpr)
+		}
 	}
-	root.handler = handlerFunc
+	return root.staticChildOrCreate(static), regExprs
 }
 
+type nodeType uint8
+
+const (
+	// nodeTypeStatic 静态节点
+	nodeTypeStatic nodeType = iota
+	// nodeTypeReg 正则路径参数节点
+	nodeTypeReg
+	// nodeTypeParam 路径参数节点
+	nodeTypeParam
+	// nodeTypeAny 通配符节点
+	nodeTypeAny
+)
+
+// node 压缩前缀树（radix tree）的节点
+// 静态节点的 path 是压缩之后的路径片段，可以跨越多段，例如 user/home，
+// 没有分叉的静态路径只占用一个节点；
+// 参数节点、正则节点和通配符节点的 path 是完整的一段，例如 :id，
+// 它们只会挂在以 / 结尾的节点上，它们的静态子节点都以 / 开头
 type node struct {
+	typ  nodeType
 	path string
 
 	// 静态匹配的节点
-	// 子 path 到左节点的映射
-	children map[string]*node
+	// indices 是每个子节点 path 的第一个字节，和 children 一一对应，
+	// 同一个节点下面的静态子节点第一个字节都不一样
+	indices  string
+	children []*node
 
-	// 通配符匹配的节点
+	// 通配符匹配的节点，* 或者 *filepath
+	// 中间的通配符只匹配一段，
+	// 最后命中的是通配符的话，它会匹配剩下的所有段
 	startChild *node
 
 	// 路径参数
 	paramChild *node
 
+	// 正则路径参数，例如 :id(^[0-9]+$)
+	regChild *node
+
+	// 路径参数、正则路径参数和具名通配符的参数名
+	paramName string
+	// 正则路径参数的正则表达式
+	regExpr *regexp.Regexp
+
 	//	业务逻辑
 	handler HandleFunc
+
+	// 注册在这个节点上的 Middleware
+	// 对这个节点以及它的所有子节点都生效
+	mdls []Middleware
+
+	// 注册路由时传入的 Middleware，只对 handler 生效
+	routeMdls []Middleware
+
+	// 注册路由时的完整路径，例如 /order/detail/:id
+	// 只有注册了 handler 的节点才有
+	route string
+	// route 里面正则路径参数的正则表达式，按照出现的顺序排列，生成 URL 的时候使用
+	regExprs []*regexp.Regexp
+
+	// 路由的元数据，用于生成文档
+	meta RouteMeta
+
+	// 路由的名字，可以为空
+	name string
+}
+
+// findRoute 查找路由
+// host 是请求的域名，可以带着端口。先在 host 对应的路由树里面查找，
+// 没有找到注册了 handler 的路由的话，再到默认的路由树里面查找
+// 路径参数追加到 params 后面，放在返回值的 pathParams 里面，
+// 调用者传入可以复用的切片，静态路由的匹配过程不会分配内存
+func (r *router) findRoute(host string, method string, path string, params Params) (matchInfo, bool) {
+	if trees := r.hostTrees(host); trees != nil {
+		info, ok := matchRoute(trees, method, path, params)
+		if ok && info.n.handler != nil {
+			return info, true
+		}
+	}
+	return matchRoute(r.trees, method, path, params)
 }
 
-func (r *router) findRoute(method string, path string) (*matchInfo, bool) {
+// matchRoute 在 trees 里面查找路由
+func matchRoute(trees map[string]*node, method string, path string, params Params) (matchInfo, bool) {
 	// 沿着树深度优先搜索
-	root, ok := r.trees[method]
+	root, ok := trees[method]
 	if !ok {
-		return nil, false
+		return matchInfo{}, false
 	}
 
+	// 去除前置后置 /
+	path = strings.Trim(path, "/")
 	// 根节点特殊处理
-	if path == "/" {
-		return &matchInfo{
-			n: root,
+	if path == "" {
+		return matchInfo{
+			n:          root,
+			pathParams: params,
+			mdls:       appendMdls(root.mdls, root.routeMdls),
 		}, true
 	}
 
-	// 去除前置后置 /
-	path = strings.Trim(path, "/")
-	var pathParams map[string]string
-	for _, seg := range strings.Split(path, "/") {
-		child, paramChild, found := root.childOf(seg)
+	// 当前的位置是 n 的第 off 个字节之后
+	// 只有 off 等于 len(n.path) 的时候，n 才是一个完整的节点
+	n, off := root, len(root.path)
+	// 沿途节点上的 Middleware，从根节点到叶子节点
+	mdls := root.mdls
+	// 最近一个注册了路由的通配符，后面匹配失败的时候退回到它，由它匹配剩下的所有段
+	// 例如注册了 /static/* 和 /static/*/api，/static/a/api/x 命中的是 /static/*
+	var fallback anyFallback
+	for i := 0; i < len(path); {
+		end := strings.IndexByte(path[i:], '/')
+		if end < 0 {
+			end = len(path)
+		} else {
+			end += i
+		}
+		seg := path[i:end]
+		child, childOff, found := n, off, true
+		if i > 0 {
+			child, childOff, found = n.matchStatic(off, "/")
+		}
+		if found {
+			child, childOff, found = child.childOf(childOff, seg)
+		}
 		if !found {
-			return nil, false
+			// 通配符匹配剩下的所有段，例如 /static/* 可以匹配 /static/css/a.css
+			// 而 /a/*/b 里面的通配符依旧只匹配一段
+			return fallback.match(path, params)
+		}
+		// 命中了路径参数或者通配符
+		if child.typ != nodeTypeStatic {
+			params = append(params, Param{Key: child.paramKey(), Value: seg})
+		}
+		if childOff == len(child.path) {
+			mdls = appendMdls(mdls, child.mdls)
 		}
-		// 命中了路径参数
-		if paramChild {
-			if pathParams == nil {
-				pathParams = make(map[string]string)
+		if child.typ == nodeTypeAny && child.handler != nil {
+			fallback = anyFallback{
+				n:        child,
+				mdls:     mdls,
+				paramIdx: len(params) - 1,
+				segStart: i,
 			}
-			// path 是 :id 这种形式
-			pathParams[child.path[1:]] = seg
 		}
-		root = child
+		n, off = child, childOff
+		i = end + 1
 	}
 
-	//return root, root.handler != nil
+	// 停在了压缩节点的中间，说明这一段只是别的路由的前缀，没有 handler
+	if off != len(n.path) {
+		return fallback.match(path, params)
+	}
+	if n.handler == nil && fallback.n != nil {
+		return fallback.match(path, params)
+	}
 	// 确实有这个节点，但不能确定有 handler
-	return &matchInfo{
-		n:          root,
-		pathParams: pathParams,
+	return matchInfo{
+		n:          n,
+		pathParams: params,
+		mdls:       appendMdls(mdls, n.routeMdls),
+	}, true
+}
+
+// anyFallback 记录匹配过程中经过的、注册了路由的通配符
+type anyFallback struct {
+	n *node
+	// 从根节点到通配符沿途的 Middleware
+	mdls []Middleware
+	// 通配符在 params 里面的下标
+	paramIdx int
+	// 通配符匹配的那一段在 path 里面的起始位置
+	segStart int
+}
+
+// match 让通配符匹配 path 从 segStart 开始剩下的所有段
+// 丢弃通配符后面匹配到的参数。没有经过这样的通配符的时候就是没有匹配上
+func (f anyFallback) match(path string, params Params) (matchInfo, bool) {
+	if f.n == nil {
+		return matchInfo{}, false
+	}
+	params = params[:f.paramIdx+1]
+	params[f.paramIdx].Value = path[f.segStart:]
+	return matchInfo{
+		n:          f.n,
+		pathParams: params,
+		mdls:       appendMdls(f.mdls, f.n.routeMdls),
 	}, true
 }
 
+// allowedMethods 找出 host 和 path 上所有注册了 handler 的方法，包括默认的路由树里面的
+// 注册了 GET 就自动支持 HEAD，OPTIONS 总是支持的
+// 没有任何方法命中的时候返回 nil
+func (r *router) allowedMethods(host string, path string) []string {
+	var res []string
+	methods := make(map[string]struct{}, len(r.trees))
+	for method := range r.trees {
+		methods[method] = struct{}{}
+	}
+	for method := range r.hostTrees(host) {
+		methods[method] = struct{}{}
+	}
+	for method := range methods {
+		info, ok := r.findRoute(host, method, path, nil)
+		if ok && info.n.handler != nil {
+			res = append(res, method)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	hasHead, hasGet, hasOptions := false, false, false
+	for _, method := range res {
+		switch method {
+		case http.MethodHead:
+			hasHead = true
+		case http.MethodGet:
+			hasGet = true
+		case http.MethodOptions:
+			hasOptions = true
+		}
+	}
+	if hasGet && !hasHead {
+		res = append(res, http.MethodHead)
+	}
+	if !hasOptions {
+		res = append(res, http.MethodOptions)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// appendMdls 追加 Middleware，但是不会修改到 mdls 的底层数组，
+// 因为 mdls 有可能就是节点上的切片
+func appendMdls(mdls []Middleware, more []Middleware) []Middleware {
+	if len(more) == 0 {
+		return mdls
+	}
+	return append(mdls[:len(mdls):len(mdls)], more...)
+}
+
+// staticChildOrCreate 在 n 后面插入静态路径 path，返回 path 结尾所在的节点
+// 和已有的子节点有公共前缀的时候，把公共前缀拆成一个新的节点
+// 已有的节点对象不会被替换，因为 Route 和路由名字都持有节点的指针
+func (n *node) staticChildOrCreate(path string) *node {
+	for path != "" {
+		idx := strings.IndexByte(n.indices, path[0])
+		if idx < 0 {
+			child := &node{
+				path: path,
+			}
+			n.indices += path[:1]
+			n.children = append(n.children, child)
+			return child
+		}
+		child := n.children[idx]
+		l := commonPrefix(child.path, path)
+		if l < len(child.path) {
+			prefix := &node{
+				path:     child.path[:l],
+				indices:  child.path[l : l+1],
+				children: []*node{child},
+			}
+			child.path = child.path[l:]
+			n.children[idx] = prefix
+			child = prefix
+		}
+		path = path[l:]
+		n = child
+	}
+	return n
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// childrenOrCreate 查找参数路由或者通配符路由的子节点，没有就创建
+// 同一个节点下面静态路由、正则路由、参数路由和通配符路由可以同时存在，
+// 但是只能有一个参数路由和一个正则路由
 func (n *node) childrenOrCreate(seg string) *node {
 	if seg[0] == ':' {
-		if n.startChild != nil {
-			panic("web: 不允许同时注册路径参数和通配符匹配，已有通配符匹配")
+		name, expr, isReg := parseParam(seg)
+		if isReg {
+			return n.childOrCreateReg(seg, name, expr)
 		}
-		n.paramChild = &node{
-			path: seg,
+		return n.childOrCreateParam(seg, name)
+	}
+
+	if n.startChild != nil {
+		if n.startChild.path != seg {
+			panic(fmt.Sprintf("web: 路由冲突，通配符路由冲突，已有 %s，新注册 %s", n.startChild.path, seg))
 		}
-		return n.paramChild
+		return n.startChild
+	}
+	// *filepath 这种形式的通配符，匹配的内容放在 filepath 里面
+	n.startChild = &node{
+		typ:       nodeTypeAny,
+		path:      seg,
+		paramName: seg[1:],
 	}
+	return n.startChild
+}
 
-	if seg == "*" {
-		if n.paramChild != nil {
-			panic("web: 不允许同时注册路径参数和通配符匹配，已有路径参数")
+func (n *node) childOrCreateParam(seg string, name string) *node {
+	if n.paramChild != nil {
+		if n.paramChild.path != seg {
+			panic(fmt.Sprintf("web: 路由冲突，参数路由冲突，已有 %s，新注册 %s", n.paramChild.path, seg))
 		}
-		if n.startChild == nil {
-			n.startChild = &node{path: "*"}
+		return n.paramChild
+	}
+	n.paramChild = &node{
+		typ:       nodeTypeParam,
+		path:      seg,
+		paramName: name,
+	}
+	return n.paramChild
+}
+
+func (n *node) childOrCreateReg(seg string, name string, expr string) *node {
+	if n.regChild != nil {
+		if n.regChild.path != seg {
+			panic(fmt.Sprintf("web: 路由冲突，正则路由冲突，已有 %s，新注册 %s", n.regChild.path, seg))
 		}
-		return n.startChild
+		return n.regChild
 	}
-	if n.children == nil {
-		n.children = map[string]*node{}
+	regExpr, err := regexp.Compile(expr)
+	if err != nil {
+		panic(fmt.Sprintf("web: 非法路由 %s，正则表达式错误 %v", seg, err))
 	}
-	res, ok := n.children[seg]
-	if !ok {
-		res = &node{
-			path: seg,
+	n.regChild = &node{
+		typ:       nodeTypeReg,
+		path:      seg,
+		paramName: name,
+		regExpr:   regExpr,
+	}
+	return n.regChild
+}
+
+// paramKey 路径参数在 PathParams 里面的 key
+// 匿名的通配符使用 *
+func (n *node) paramKey() string {
+	if n.typ == nodeTypeAny && n.paramName == "" {
+		return "*"
+	}
+	return n.paramName
+}
+
+// parseParam 解析 :id 或者 :id(^[0-9]+$) 形式的路径参数
+// 第一个返回值是参数名
+// 第二个返回值是正则表达式
+// 第三个标记是否是正则路由
+func parseParam(seg string) (string, string, bool) {
+	name := seg[1:]
+	expr := ""
+	isReg := false
+	if idx := strings.IndexByte(seg, '('); idx > 0 {
+		if seg[len(seg)-1] != ')' {
+			panic(fmt.Sprintf("web: 非法路由 %s，正则路由必须以 ) 结尾", seg))
+		}
+		name = seg[1:idx]
+		expr = seg[idx+1 : len(seg)-1]
+		isReg = true
+		if expr == "" {
+			panic(fmt.Sprintf("web: 非法路由 %s，正则表达式不能为空", seg))
 		}
-		n.children[seg] = res
 	}
-	return res
+	if name == "" {
+		panic(fmt.Sprintf("web: 非法路由 %s，缺少参数名", seg))
+	}
+	return name, expr, isReg
 }
 
-// childOf 优先考虑静态匹配，匹配不上，再考虑通配符
-// 第一个返回值是子节点
-// 第二个是标记是否是路径参数
-// 第三个标记是否命中
-func (n *node) childOf(path string) (*node, bool, bool) {
-	if n.children == nil {
-		if n.paramChild != nil {
-			return n.paramChild, true, true
+// childOf 从 n 的第 off 个字节之后开始，按照优先级匹配一段 seg：
+// 静态匹配 > 正则匹配 > 路径参数 > 通配符匹配
+// 静态匹配只有完整地匹配了一段才算命中，命中之后不会再回退
+// 返回 seg 结尾所在的节点和偏移量，第三个返回值标记是否命中
+func (n *node) childOf(off int, seg string) (*node, int, bool) {
+	if seg != "" {
+		if child, childOff, ok := n.matchStatic(off, seg); ok && child.isSegEnd(childOff) {
+			return child, childOff, true
 		}
-		return n.startChild, false, n.startChild != nil
 	}
-	child, ok := n.children[path]
-	if !ok {
-		if n.paramChild != nil {
-			return n.paramChild, false, true
+	// 参数路由和通配符路由只挂在节点的末尾
+	if off != len(n.path) {
+		return nil, 0, false
+	}
+	if n.regChild != nil && n.regChild.regExpr.MatchString(seg) {
+		return n.regChild, len(n.regChild.path), true
+	}
+	if n.paramChild != nil {
+		return n.paramChild, len(n.paramChild.path), true
+	}
+	if n.startChild != nil {
+		return n.startChild, len(n.startChild.path), true
+	}
+	return nil, 0, false
+}
+
+// matchStatic 从 n 的第 off 个字节之后开始，沿着静态节点匹配 path
+// 返回 path 结尾所在的节点和偏移量
+func (n *node) matchStatic(off int, path string) (*node, int, bool) {
+	for path != "" {
+		if off == len(n.path) {
+			idx := strings.IndexByte(n.indices, path[0])
+			if idx < 0 {
+				return nil, 0, false
+			}
+			n, off = n.children[idx], 0
+		}
+		l := len(n.path) - off
+		if l > len(path) {
+			l = len(path)
+		}
+		if n.path[off:off+l] != path[:l] {
+			return nil, 0, false
 		}
-		return n.startChild, false, n.startChild != nil
+		off += l
+		path = path[l:]
+	}
+	return n, off, true
+}
+
+// isSegEnd n 的第 off 个字节之后是不是一段的结尾
+// 也就是说，在按段切分的路由树里面，这里是不是一个节点
+func (n *node) isSegEnd(off int) bool {
+	if off < len(n.path) {
+		return n.path[off] == '/'
 	}
-	return child, false, ok
+	return n.handler != nil || n.mdls != nil || strings.IndexByte(n.indices, '/') >= 0
 }
 
 type matchInfo struct {
 	n          *node
-	pathParams map[string]string
+	pathParams Params
+	// 命中路由之后需要执行的 Middleware
+	mdls []Middleware
 }
@@ -0,0 +1,195 @@
+package web
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var benchRoutes = []string{
+	"/",
+	"/user",
+	"/user/home",
+	"/user/profile/settings",
+	"/user/:id",
+	"/user/:id/orders",
+	"/order/detail",
+	"/order/detail/:id(^[0-9]+$)",
+	"/order/create",
+	"/static/*filepath",
+	"/api/v1/users",
+	"/api/v1/users/:id",
+	"/api/v1/orders",
+	"/api/v1/orders/:id",
+	"/api/v2/users",
+	"/api/v2/orders",
+}
+
+var benchPaths = []struct {
+	name string
+	path string
+}{
+	{name: "static", path: "/api/v1/orders"},
+	{name: "static long", path: "/user/profile/settings"},
+	{name: "param", path: "/api/v1/users/123"},
+	{name: "regexp", path: "/order/detail/123"},
+	{name: "wildcard", path: "/static/css/a.css"},
+}
+
+func BenchmarkRouter_findRoute(b *testing.B) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	sr := &segmentRouter{trees: map[string]*segmentNode{}}
+	for _, route := range benchRoutes {
+		r.addRoute(http.MethodGet, route, mockHandler)
+		sr.addRoute(http.MethodGet, route, mockHandler)
+	}
+
+	for _, bp := range benchPaths {
+		b.Run("radix "+bp.name, func(b *testing.B) {
+			// 和 Context 一样复用 PathParams
+			params := make(Params, 0, 4)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				info, ok := r.findRoute("", http.MethodGet, bp.path, params[:0])
+				if !ok || info.n.handler == nil {
+					b.Fatal("没有命中路由")
+				}
+			}
+		})
+		b.Run("segment "+bp.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				n, _, ok := sr.findRoute(http.MethodGet, bp.path)
+				if !ok || n.handler == nil {
+					b.Fatal("没有命中路由")
+				}
+			}
+		})
+	}
+}
+
+func TestRouter_findRouteAllocs(t *testing.T) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	for _, route := range benchRoutes {
+		r.addRoute(http.MethodGet, route, mockHandler)
+	}
+	params := make(Params, 0, 4)
+	for _, bp := range benchPaths {
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = r.findRoute("", http.MethodGet, bp.path, params[:0])
+		})
+		if allocs != 0 {
+			t.Errorf("%s 分配了 %v 次内存", bp.name, allocs)
+		}
+	}
+}
+
+// segmentRouter 按段切分的路由树，也就是改成压缩前缀树之前的实现
+// 只用来做性能对比，省略了 Middleware 和各种校验
+type segmentRouter struct {
+	trees map[string]*segmentNode
+}
+
+type segmentNode struct {
+	path       string
+	children   map[string]*segmentNode
+	startChild *segmentNode
+	paramChild *segmentNode
+	regChild   *segmentNode
+	paramName  string
+	regExpr    *regexp.Regexp
+	handler    HandleFunc
+}
+
+func (r *segmentRouter) addRoute(method string, path string, handler HandleFunc) {
+	root, ok := r.trees[method]
+	if !ok {
+		root = &segmentNode{path: "/"}
+		r.trees[method] = root
+	}
+	if path == "/" {
+		root.handler = handler
+		return
+	}
+	for _, seg := range strings.Split(path[1:], "/") {
+		root = root.childOrCreate(seg)
+	}
+	root.handler = handler
+}
+
+func (n *segmentNode) childOrCreate(seg string) *segmentNode {
+	switch seg[0] {
+	case ':':
+		name, expr, isReg := parseParam(seg)
+		if isReg {
+			if n.regChild == nil {
+				n.regChild = &segmentNode{path: seg, paramName: name, regExpr: regexp.MustCompile(expr)}
+			}
+			return n.regChild
+		}
+		if n.paramChild == nil {
+			n.paramChild = &segmentNode{path: seg, paramName: name}
+		}
+		return n.paramChild
+	case '*':
+		if n.startChild == nil {
+			n.startChild = &segmentNode{path: seg, paramName: seg[1:]}
+		}
+		return n.startChild
+	}
+	if n.children == nil {
+		n.children = map[string]*segmentNode{}
+	}
+	res, ok := n.children[seg]
+	if !ok {
+		res = &segmentNode{path: seg}
+		n.children[seg] = res
+	}
+	return res
+}
+
+func (r *segmentRouter) findRoute(method string, path string) (*segmentNode, map[string]string, bool) {
+	root, ok := r.trees[method]
+	if !ok {
+		return nil, nil, false
+	}
+	if path == "/" {
+		return root, nil, true
+	}
+	var pathParams map[string]string
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segs {
+		child, isParam, found := root.childOf(seg)
+		if !found {
+			if root.path[0] == '*' && root.handler != nil {
+				pathParams[root.paramName] = strings.Join(segs[i-1:], "/")
+				break
+			}
+			return nil, nil, false
+		}
+		if isParam || child.path[0] == '*' {
+			if pathParams == nil {
+				pathParams = make(map[string]string)
+			}
+			pathParams[child.paramName] = seg
+		}
+		root = child
+	}
+	return root, pathParams, true
+}
+
+func (n *segmentNode) childOf(seg string) (*segmentNode, bool, bool) {
+	if child, ok := n.children[seg]; ok {
+		return child, false, true
+	}
+	if n.regChild != nil && n.regChild.regExpr.MatchString(seg) {
+		return n.regChild, true, true
+	}
+	if n.paramChild != nil {
+		return n.paramChild, true, true
+	}
+	return n.startChild, false, n.startChild != nil
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"reflect"
+	"regexp"
 	"testing"
 )
 
@@ -35,6 +36,10 @@ func TestRouter_addRoute(t *testing.T) {
 			method: http.MethodGet,
 			path:   "/order/detail/:id",
 		},
+		{
+			method: http.MethodGet,
+			path:   "/order/detail/:id(^[0-9]+$)",
+		},
 		{
 			method: http.MethodGet,
 			path:   "/order/*",
@@ -72,72 +77,104 @@ func TestRouter_addRoute(t *testing.T) {
 	}
 
 	//	在这里断言路由树和你的预期的一模一样
+	// 没有分叉的静态路径被压缩到一个节点里面
 	wantRouter := &router{
 		trees: map[string]*node{
 			http.MethodGet: {
 				path:    "/",
 				handler: mockHandler,
-				children: map[string]*node{
-					"user": {
+				indices: "uo",
+				children: []*node{
+					{
 						path:    "user",
 						handler: mockHandler,
-						children: map[string]*node{
-							"home": {
-								path:    "home",
+						indices: "/",
+						children: []*node{
+							{
+								path:    "/home",
 								handler: mockHandler,
 							},
 						},
 					},
-					"order": {
-						path: "order",
-						children: map[string]*node{
-							"detail": {
+					{
+						path:    "order/",
+						indices: "d",
+						children: []*node{
+							{
 								path:    "detail",
 								handler: mockHandler,
-								paramChild: &node{
-									path:    ":id",
-									handler: mockHandler,
+								indices: "/",
+								children: []*node{
+									{
+										path: "/",
+										paramChild: &node{
+											typ:       nodeTypeParam,
+											path:      ":id",
+											paramName: "id",
+											handler:   mockHandler,
+										},
+										regChild: &node{
+											typ:       nodeTypeReg,
+											path:      ":id(^[0-9]+$)",
+											paramName: "id",
+											regExpr:   regexp.MustCompile("^[0-9]+$"),
+											handler:   mockHandler,
+										},
+									},
 								},
 							},
 						},
 						startChild: &node{
+							typ:     nodeTypeAny,
 							path:    "*",
 							handler: mockHandler,
 						},
 					},
 				},
 				startChild: &node{
+					typ:     nodeTypeAny,
 					path:    "*",
 					handler: mockHandler,
-					children: map[string]*node{
-						"abc": {
-							path: "abc",
+					indices: "/",
+					children: []*node{
+						{
+							path:    "/",
+							indices: "a",
+							children: []*node{
+								{
+									path:    "abc",
+									handler: mockHandler,
+									indices: "/",
+									children: []*node{
+										{
+											path: "/",
+											startChild: &node{
+												typ:     nodeTypeAny,
+												path:    "*",
+												handler: mockHandler,
+											},
+										},
+									},
+								},
+							},
 							startChild: &node{
+								typ:     nodeTypeAny,
 								path:    "*",
 								handler: mockHandler,
 							},
-							handler: mockHandler,
 						},
 					},
-					startChild: &node{
-						path:    "*",
-						handler: mockHandler,
-					},
 				},
 			},
 			http.MethodPost: {
-				path: "/",
-				children: map[string]*node{
-					"order": {
-						path: "order",
-						children: map[string]*node{
-							"create": {
-								path:    "create",
-								handler: mockHandler,
-							},
-						},
+				path:    "/",
+				indices: "ol",
+				children: []*node{
+					{
+						path:    "order/create",
+						handler: mockHandler,
 					},
-					"login": {
+					{
 						path:    "login",
 						handler: mockHandler,
 					},
@@ -176,17 +213,56 @@ func TestRouter_addRoute(t *testing.T) {
 	// 可用的 http method，要不要校验？AddRoute 改为 addRoute，变私有，用户不能使用
 	// mockHandler 为 nil，要不要校验？用户决定，一般不会为 nil，如果为 nil 相当于没有注册路由
 
+	// 路径参数、正则路由和通配符可以同时注册
 	r = newRouter()
 	r.addRoute(http.MethodGet, "/a/*", mockHandler)
+	r.addRoute(http.MethodGet, "/a/:id", mockHandler)
+	r.addRoute(http.MethodGet, "/a/:id(^[0-9]+$)", mockHandler)
+	assert.NotNil(t, r.trees[http.MethodGet].children[0].startChild)
+	assert.NotNil(t, r.trees[http.MethodGet].children[0].paramChild)
+	assert.NotNil(t, r.trees[http.MethodGet].children[0].regChild)
+
+	r = newRouter()
+	r.addRoute(http.MethodGet, "/a/:id(^[0-9]+$)", mockHandler)
 	assert.Panicsf(t, func() {
-		r.addRoute(http.MethodGet, "/a/:id", mockHandler)
-	}, "web: 不允许同时注册路径参数和通配符匹配，已有通配符匹配")
+		r.addRoute(http.MethodGet, "/a/:id(^[0-9]+$)", mockHandler)
+	}, "web: 路由冲突， 重复注册[/a/:id(^[0-9]+$)]")
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/a/:id(^[a-z]+$)", mockHandler)
+	}, "web: 路由冲突，正则路由冲突，已有 :id(^[0-9]+$)，新注册 :id(^[a-z]+$)")
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/b/:id([0-9]+", mockHandler)
+	}, "web: 非法路由 :id([0-9]+，正则路由必须以 ) 结尾")
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/b/:id([0-9+)", mockHandler)
+	}, "web: 非法路由 :id([0-9+)，正则表达式错误")
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/b/:id()", mockHandler)
+	}, "web: 非法路由 :id()，正则表达式不能为空")
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/b/:([0-9]+)", mockHandler)
+	}, "web: 非法路由 :([0-9]+)，缺少参数名")
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/b/:", mockHandler)
+	}, "web: 非法路由 :，缺少参数名")
+
+	r = newRouter()
+	r.addRoute(http.MethodGet, "/static/*filepath", mockHandler)
+	assert.Equal(t, "filepath", r.trees[http.MethodGet].children[0].startChild.paramName)
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/static/*", mockHandler)
+	}, "web: 路由冲突，通配符路由冲突，已有 *filepath，新注册 *")
 
 	r = newRouter()
 	r.addRoute(http.MethodGet, "/a/:id", mockHandler)
+	// 同名的路径参数复用同一个节点
+	r.addRoute(http.MethodGet, "/a/:id/b", mockHandler)
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/a/:id", mockHandler)
+	}, "web: 路由冲突， 重复注册[/a/:id]")
 	assert.Panicsf(t, func() {
-		r.addRoute(http.MethodGet, "/a/*", mockHandler)
-	}, "web: 不允许同时注册路径参数和通配符匹配，已有路径参数")
+		r.addRoute(http.MethodGet, "/a/:name", mockHandler)
+	}, "web: 路由冲突，参数路由冲突，已有 :id，新注册 :name")
 }
 
 // string 返回错误信息，帮助排查
@@ -211,6 +287,14 @@ func (n *node) equal(y *node) (string, bool) {
 		return fmt.Sprintf("%s 节点 path 不相等 x %s, y %s", n.path, n.path, y.path), false
 	}
 
+	if n.typ != y.typ {
+		return fmt.Sprintf("%s 节点类型不相等 x %d, y %d", n.path, n.typ, y.typ), false
+	}
+
+	if n.indices != y.indices {
+		return fmt.Sprintf("%s 节点 indices 不相等 x %s, y %s", n.path, n.indices, y.indices), false
+	}
+
 	if n.startChild != nil {
 		msg, ok := n.startChild.equal(y.startChild)
 		if !ok {
@@ -225,6 +309,22 @@ func (n *node) equal(y *node) (string, bool) {
 		}
 	}
 
+	if n.regChild != nil {
+		msg, ok := n.regChild.equal(y.regChild)
+		if !ok {
+			return msg, ok
+		}
+	}
+
+	if n.paramName != y.paramName {
+		return fmt.Sprintf("%s 节点参数名不相等 x %s, y %s", n.path, n.paramName, y.paramName), false
+	}
+
+	if (n.regExpr == nil) != (y.regExpr == nil) ||
+		(n.regExpr != nil && n.regExpr.String() != y.regExpr.String()) {
+		return fmt.Sprintf("%s 节点正则表达式不相等 x %s, y %s", n.path, n.regExpr, y.regExpr), false
+	}
+
 	// 比较两个方法是否相等
 	nhv := reflect.ValueOf(n.handler)
 	yhv := reflect.ValueOf(y.handler)
@@ -240,11 +340,7 @@ func (n *node) equal(y *node) (string, bool) {
 	}
 
 	for k, v := range n.children {
-		yv, ok := y.children[k]
-		if !ok {
-			return fmt.Sprintf("%s 目标节点缺少子节点 %s", n.path, k), false
-		}
-		if str, ok := v.equal(yv); !ok {
+		if str, ok := v.equal(y.children[k]); !ok {
 			return n.path + "-" + str, ok
 		}
 	}
@@ -292,6 +388,34 @@ func TestRouter_findRoute(t *testing.T) {
 			method: http.MethodPost,
 			path:   "/login/:username",
 		},
+		{
+			method: http.MethodGet,
+			path:   "/user/:id(^[0-9]+$)",
+		},
+		{
+			method: http.MethodGet,
+			path:   "/user/:name",
+		},
+		{
+			method: http.MethodGet,
+			path:   "/user/*",
+		},
+		{
+			method: http.MethodGet,
+			path:   "/static/*filepath",
+		},
+		{
+			method: http.MethodGet,
+			path:   "/proxy/*/api",
+		},
+		{
+			method: http.MethodGet,
+			path:   "/assets/*",
+		},
+		{
+			method: http.MethodGet,
+			path:   "/assets/*/api",
+		},
 	}
 
 	r := newRouter()
@@ -337,28 +461,160 @@ func TestRouter_findRoute(t *testing.T) {
 			wantFound: true,
 			info: &matchInfo{
 				n: &node{
+					typ:     nodeTypeAny,
+					handler: mockHandler,
+					path:    "*",
+				},
+				pathParams: Params{{Key: "*", Value: "abc"}},
+			},
+		},
+		{
+			// 末尾的通配符匹配剩下的所有段
+			name:      "order start multiple segments",
+			method:    http.MethodGet,
+			path:      "/order/a/b/c",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					typ:     nodeTypeAny,
 					handler: mockHandler,
 					path:    "*",
 				},
+				pathParams: Params{{Key: "*", Value: "a/b/c"}},
+			},
+		},
+		{
+			name:      "static filepath",
+			method:    http.MethodGet,
+			path:      "/static/css/a.css",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					typ:       nodeTypeAny,
+					handler:   mockHandler,
+					path:      "*filepath",
+					paramName: "filepath",
+				},
+				pathParams: Params{{Key: "filepath", Value: "css/a.css"}},
+			},
+		},
+		{
+			// 中间的通配符只匹配一段
+			name:      "middle start",
+			method:    http.MethodGet,
+			path:      "/proxy/a/b/api",
+			wantFound: false,
+		},
+		{
+			name:      "middle start api",
+			method:    http.MethodGet,
+			path:      "/proxy/a/api",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					handler: mockHandler,
+					path:    "/api",
+				},
+				pathParams: Params{{Key: "*", Value: "a"}},
+			},
+		},
+		{
+			name:      "assets api",
+			method:    http.MethodGet,
+			path:      "/assets/a/api",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					handler: mockHandler,
+					path:    "/api",
+				},
+				pathParams: Params{{Key: "*", Value: "a"}},
+			},
+		},
+		{
+			// /assets/*/api 后面匹配失败，退回到 /assets/*
+			name:      "assets api fallback",
+			method:    http.MethodGet,
+			path:      "/assets/a/api/x",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					typ:     nodeTypeAny,
+					path:    "*",
+					handler: mockHandler,
+					indices: "/",
+					children: []*node{
+						{
+							path:    "/api",
+							handler: mockHandler,
+						},
+					},
+				},
+				pathParams: Params{{Key: "*", Value: "a/api/x"}},
+			},
+		},
+		{
+			name:      "assets multiple segments",
+			method:    http.MethodGet,
+			path:      "/assets/a/b",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					typ:     nodeTypeAny,
+					path:    "*",
+					handler: mockHandler,
+					indices: "/",
+					children: []*node{
+						{
+							path:    "/api",
+							handler: mockHandler,
+						},
+					},
+				},
+				pathParams: Params{{Key: "*", Value: "a/b"}},
 			},
 		},
 		{
 			// 命中了，但是没有 handler
-			name:      "order",
+			name:      "middle start without handler",
 			method:    http.MethodGet,
-			path:      "/order",
+			path:      "/proxy/a",
 			wantFound: true,
 			info: &matchInfo{
 				n: &node{
-					//handler: mockHandler,
-					path: "order",
-					children: map[string]*node{
-						"detail": &node{
-							path:    "detail",
+					typ:     nodeTypeAny,
+					path:    "*",
+					indices: "/",
+					children: []*node{
+						{
+							path:    "/api",
 							handler: mockHandler,
 						},
 					},
 				},
+				pathParams: Params{{Key: "*", Value: "a"}},
+			},
+		},
+		{
+			// /order 在压缩节点 order/ 的中间，没有 handler，等同于没有命中
+			name:      "order",
+			method:    http.MethodGet,
+			path:      "/order",
+			wantFound: false,
+		},
+		{
+			// 静态匹配要完整地匹配一段，/order/det 不会命中 detail
+			name:      "order prefix of detail",
+			method:    http.MethodGet,
+			path:      "/order/det",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					typ:     nodeTypeAny,
+					path:    "*",
+					handler: mockHandler,
+				},
+				pathParams: Params{{Key: "*", Value: "det"}},
 			},
 		},
 		{
@@ -389,19 +645,81 @@ func TestRouter_findRoute(t *testing.T) {
 			wantFound: true,
 			info: &matchInfo{
 				n: &node{
-					path:    ":username",
+					typ:       nodeTypeParam,
+					path:      ":username",
+					paramName: "username",
+					handler:   mockHandler,
+				},
+				pathParams: Params{{Key: "username", Value: "code"}},
+			},
+		},
+		{
+			// 静态匹配优先
+			name:      "user home",
+			method:    http.MethodGet,
+			path:      "/user/home",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					path:    "home",
 					handler: mockHandler,
 				},
-				pathParams: map[string]string{
-					"username": "code",
+			},
+		},
+		{
+			// 正则匹配优先于路径参数
+			name:      "user id",
+			method:    http.MethodGet,
+			path:      "/user/123",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					typ:       nodeTypeReg,
+					path:      ":id(^[0-9]+$)",
+					paramName: "id",
+					regExpr:   regexp.MustCompile("^[0-9]+$"),
+					handler:   mockHandler,
+				},
+				pathParams: Params{{Key: "id", Value: "123"}},
+			},
+		},
+		{
+			// home 的前缀不算静态匹配，退化为路径参数
+			name:      "user home prefix",
+			method:    http.MethodGet,
+			path:      "/user/ho",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					typ:       nodeTypeParam,
+					path:      ":name",
+					paramName: "name",
+					handler:   mockHandler,
+				},
+				pathParams: Params{{Key: "name", Value: "ho"}},
+			},
+		},
+		{
+			// 正则没有匹配上，退化为路径参数
+			name:      "user name",
+			method:    http.MethodGet,
+			path:      "/user/Tom",
+			wantFound: true,
+			info: &matchInfo{
+				n: &node{
+					typ:       nodeTypeParam,
+					path:      ":name",
+					paramName: "name",
+					handler:   mockHandler,
 				},
+				pathParams: Params{{Key: "name", Value: "Tom"}},
 			},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			info, found := r.findRoute(tc.method, tc.path)
+			info, found := r.findRoute("", tc.method, tc.path, nil)
 			assert.Equal(t, tc.wantFound, found)
 			if !found {
 				return
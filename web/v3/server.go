@@ -1,8 +1,12 @@
 package web
 
 import (
+	"context"
+	"leanring-go/validate"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 )
 
 type HandleFunc func(ctx *Context)
@@ -17,11 +21,21 @@ type Server interface {
 	// addr 是监听地址
 	Start(addr string) error
 
+	// StartTLS 启动 HTTPS 服务器，支持 HTTP/2
+	// certFile 和 keyFile 是 PEM 格式的证书和私钥，文件变化之后会自动重新加载
+	StartTLS(addr string, certFile string, keyFile string) error
+
+	// Shutdown 优雅退出
+	// 不再接收新的连接，并且等待已有的请求处理完毕，最多等到 ctx 超时
+	Shutdown(ctx context.Context) error
+
 	// addRoute 路由注册功能
 	// method 是 HTTP 方法
 	// path 是路由
 	// handleFunc 你的业务逻辑
-	addRoute(method string, path string, handleFunc HandleFunc)
+	// mdls 是只作用在这条路由上的 Middleware
+	// 返回值是路由对应的节点
+	addRoute(method string, path string, handleFunc HandleFunc, mdls ...Middleware) *node
 	// AddRoute1 支持注册多个 handleFunc，没有必要提供
 	//AddRoute1(method string, path string, handlerFunc ...HandleFunc)
 }
@@ -36,35 +50,137 @@ type HTTPServer struct {
 	// 因为 HTTPServer 提供给用户的是指针类型（*HTTPServer)
 	// 所有可以直接组合 router，否则需要使用 *router
 	router
+
+	// mdls 是作用在整个服务器上的 Middleware，
+	// 不管路由有没有命中都会执行
+	mdls []Middleware
+
+	// server 真正处理连接的 http.Server
+	// 在创建 HTTPServer 的时候就初始化，这样 Shutdown 和 Start 不会有并发问题
+	server *http.Server
+
+	// 生命周期回调，按照注册的顺序执行
+	startHooks    []Hook
+	shutdownHooks []Hook
+	stopHooks     []Hook
+
+	// 模板引擎，Context.Render 使用
+	tplEngine TemplateEngine
+
+	// 编解码器，Context.Negotiate 和 Context.Bind 使用
+	// 为 nil 的时候使用默认的编解码器
+	codecs *CodecRegistry
+
+	// 绑定请求之后使用的校验器，为 nil 的时候使用默认的校验器
+	validator validate.Validator
+
+	// 复用 Context，主要是复用 PathParams 的底层数组
+	ctxPool sync.Pool
 }
 
-func NewHTTPServer() *HTTPServer {
-	return &HTTPServer{
+// Hook 生命周期回调
+type Hook func(ctx context.Context) error
+
+// HTTPServerOption Option 模式，用于定制 HTTPServer
+type HTTPServerOption func(server *HTTPServer)
+
+func NewHTTPServer(opts ...HTTPServerOption) *HTTPServer {
+	res := &HTTPServer{
 		router: newRouter(),
 	}
+	res.server = &http.Server{
+		Handler: res,
+	}
+	res.ctxPool.New = func() any {
+		return &Context{}
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// ServerWithMiddleware 注册服务器级别的 Middleware
+func ServerWithMiddleware(mdls ...Middleware) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.mdls = append(server.mdls, mdls...)
+	}
+}
+
+// Use 追加服务器级别的 Middleware，效果和 ServerWithMiddleware 一样
+func (h *HTTPServer) Use(mdls ...Middleware) {
+	h.mdls = append(h.mdls, mdls...)
+}
+
+// UseRoute 在某个路由上注册 Middleware
+// 注册在前缀上的 Middleware 对它下面所有的路由都生效，
+// 例如在 /user 上注册的 Middleware 对 /user/home 也生效
+func (h *HTTPServer) UseRoute(method string, path string, mdls ...Middleware) {
+	h.addMiddlewares(method, path, mdls...)
 }
 
 // ServeHTTP 处理请求的入口
 func (h *HTTPServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	// 框架代码
-	ctx := &Context{
-		Req:  request,
-		Resp: writer,
+	ctx := h.ctxPool.Get().(*Context)
+	ctx.reset(request, writer, h)
+	// 服务器级别的 Middleware 包在最外层，
+	// 这样即便是路由没有命中也会经过它们
+	root := buildChain(h.serve, h.mdls)
+	root(ctx)
+	// 所有的 Middleware 都执行完了，才把响应写回去
+	h.flushResp(ctx)
+	h.ctxPool.Put(ctx)
+}
+
+// flushResp 把 Context 上的响应写回给客户端
+// 直接使用 ctx.Resp 写响应的 handler 不受影响，只要不设置 RespStatusCode 和 RespData
+func (h *HTTPServer) flushResp(ctx *Context) {
+	if ctx.RespStatusCode > 0 {
+		ctx.Resp.WriteHeader(ctx.RespStatusCode)
+	}
+	// HEAD 请求不需要响应体
+	if len(ctx.RespData) > 0 && ctx.Req.Method != http.MethodHead {
+		_, _ = ctx.Resp.Write(ctx.RespData)
 	}
-	h.serve(ctx)
 }
 
 func (h *HTTPServer) serve(ctx *Context) {
 	//	接下来就是查看路由，并且执行命中的业务逻辑
-	info, ok := h.findRoute(ctx.Req.Method, ctx.Req.URL.Path)
+	info, ok := h.findRoute(ctx.Req.Host, ctx.Req.Method, ctx.Req.URL.Path, ctx.PathParams[:0])
+	if (!ok || info.n.handler == nil) && ctx.Req.Method == http.MethodHead {
+		// 没有注册 HEAD 的话，使用 GET 的 handler，响应体在写回的时候丢弃
+		info, ok = h.findRoute(ctx.Req.Host, http.MethodGet, ctx.Req.URL.Path, ctx.PathParams[:0])
+	}
 	if !ok || info.n.handler == nil {
-		// 路由没有命中，返回 404
-		ctx.Resp.WriteHeader(404)
-		_, _ = ctx.Resp.Write([]byte("NOT FOUND"))
+		h.serveNoRoute(ctx)
 		return
 	}
 	ctx.PathParams = info.pathParams
-	info.n.handler(ctx)
+	ctx.MatchedRoute = info.n.route
+	// 路由上的 Middleware 只有命中之后才执行
+	root := buildChain(info.n.handler, info.mdls)
+	root(ctx)
+}
+
+// serveNoRoute 处理当前方法下没有命中路由的请求
+// 如果别的方法注册了这个路由，OPTIONS 请求直接返回 Allow，
+// 其余的请求返回 405，否则返回 404
+func (h *HTTPServer) serveNoRoute(ctx *Context) {
+	allowed := h.allowedMethods(ctx.Req.Host, ctx.Req.URL.Path)
+	if len(allowed) == 0 {
+		// 路由没有命中，返回 404
+		ctx.RespStatusCode = http.StatusNotFound
+		ctx.RespData = []byte("NOT FOUND")
+		return
+	}
+	ctx.Resp.Header().Set("Allow", strings.Join(allowed, ", "))
+	if ctx.Req.Method == http.MethodOptions {
+		ctx.RespStatusCode = http.StatusNoContent
+		return
+	}
+	ctx.RespStatusCode = http.StatusMethodNotAllowed
+	ctx.RespData = []byte("METHOD NOT ALLOWED")
 }
 
 //func (h *HTTPServer) addRoute(method string, path string, handlerFunc HandleFunc) {
@@ -72,28 +188,115 @@ func (h *HTTPServer) serve(ctx *Context) {
 //	//panic("implement me")
 //}
 
-func (h *HTTPServer) Get(path string, handleFunc HandleFunc) {
-	h.addRoute(http.MethodGet, path, handleFunc)
+func (h *HTTPServer) Get(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return &Route{r: &h.router, n: h.addRoute(http.MethodGet, path, handleFunc, mdls...)}
+}
+
+func (h *HTTPServer) POST(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return &Route{r: &h.router, n: h.addRoute(http.MethodPost, path, handleFunc, mdls...)}
+}
+
+func (h *HTTPServer) PUT(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return &Route{r: &h.router, n: h.addRoute(http.MethodPut, path, handleFunc, mdls...)}
+}
+
+func (h *HTTPServer) DELETE(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return &Route{r: &h.router, n: h.addRoute(http.MethodDelete, path, handleFunc, mdls...)}
+}
+
+func (h *HTTPServer) PATCH(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return &Route{r: &h.router, n: h.addRoute(http.MethodPatch, path, handleFunc, mdls...)}
+}
+
+func (h *HTTPServer) HEAD(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return &Route{r: &h.router, n: h.addRoute(http.MethodHead, path, handleFunc, mdls...)}
+}
+
+func (h *HTTPServer) OPTIONS(path string, handleFunc HandleFunc, mdls ...Middleware) *Route {
+	return &Route{r: &h.router, n: h.addRoute(http.MethodOptions, path, handleFunc, mdls...)}
+}
+
+// OnStart 注册启动回调，在开始监听端口之后、接收请求之前执行
+// 比如往注册中心注册一下这个实例，执行一些业务所需的前置条件
+// 需要在 Start 之前注册
+func (h *HTTPServer) OnStart(hooks ...Hook) {
+	h.startHooks = append(h.startHooks, hooks...)
 }
 
-func (h *HTTPServer) POST(path string, handleFunc HandleFunc) {
-	h.addRoute(http.MethodPost, path, handleFunc)
+// OnShutdown 注册退出回调，在 Shutdown 停止接收新的连接之前执行
+// 比如从注册中心注销这个实例，这个时候服务器还在处理请求，
+// 注册中心通知到客户端之前发过来的请求不会失败
+// 需要在 Shutdown 之前注册
+func (h *HTTPServer) OnShutdown(hooks ...Hook) {
+	h.shutdownHooks = append(h.shutdownHooks, hooks...)
 }
 
-func (h *HTTPServer) OPTIONS(path string, handleFunc HandleFunc) {
-	h.addRoute(http.MethodOptions, path, handleFunc)
+// OnStop 注册停止回调，在 Shutdown 等待请求处理完毕之后执行
+// 比如刷新缓存，关闭数据库连接
+// 需要在 Shutdown 之前注册
+func (h *HTTPServer) OnStop(hooks ...Hook) {
+	h.stopHooks = append(h.stopHooks, hooks...)
 }
 
+// Start 启动服务器，会一直阻塞直到服务器退出
+// 因为 Shutdown 而退出的时候返回 nil
 func (h *HTTPServer) Start(addr string) error {
+	l, err := h.listen(addr)
+	if err != nil {
+		return err
+	}
+	return serveErr(h.server.Serve(l))
+}
+
+// listen 监听端口，然后执行启动回调
+func (h *HTTPServer) listen(addr string) (net.Listener, error) {
 	// 可以进行生命周期管理
 	l, err := net.Listen("tcp", addr)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	// 在这里，让用户注册所谓的 after start 回调
+	for _, hook := range h.startHooks {
+		if err = hook(context.Background()); err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// serveErr 因为 Shutdown 而退出不算错误
+func serveErr(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Shutdown 优雅退出
+// 先依次执行退出回调，然后停止接收新的连接，等待已有的请求处理完毕，最后依次执行停止回调
+// ctx 超时的时候，依旧会执行所有的回调，回调自己决定要不要检查 ctx
+// 所有的回调都会执行，返回第一个遇到的 error
+func (h *HTTPServer) Shutdown(ctx context.Context) error {
+	err := runHooks(ctx, h.shutdownHooks)
+	if er := h.server.Shutdown(ctx); er != nil && err == nil {
+		err = er
+	}
+	if er := runHooks(ctx, h.stopHooks); er != nil && err == nil {
+		err = er
+	}
+	return err
+}
+
+// runHooks 执行所有的回调，返回第一个遇到的 error
+func runHooks(ctx context.Context, hooks []Hook) error {
+	var err error
+	for _, hook := range hooks {
+		if er := hook(ctx); er != nil && err == nil {
+			err = er
+		}
 	}
-	// 在这里，可以让用户注册所谓的 after start 回调
-	// 比如往 admin 注册一下这个实例
-	// 执行一些业务所需的前置条件
-	return http.Serve(l, h)
+	return err
 }
 
 //func (h *HTTPServer) Start1(addr string) error {
@@ -1 +1,382 @@
 package web
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPServer_Middleware(t *testing.T) {
+	var logs []string
+	mdl := func(name string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				logs = append(logs, name+" before")
+				next(ctx)
+				logs = append(logs, name+" after")
+			}
+		}
+	}
+	h := NewHTTPServer(ServerWithMiddleware(mdl("server1")))
+	h.Use(mdl("server2"))
+	h.UseRoute(http.MethodGet, "/", mdl("root"))
+	h.UseRoute(http.MethodGet, "/user", mdl("user"))
+	h.Get("/user/:id", func(ctx *Context) {
+		logs = append(logs, "handler")
+	}, mdl("id"))
+	h.Get("/order", func(ctx *Context) {
+		logs = append(logs, "handler")
+	})
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantLogs []string
+	}{
+		{
+			// 前缀上的 Middleware 对子路由生效
+			name: "user id",
+			path: "/user/123",
+			wantLogs: []string{
+				"server1 before", "server2 before",
+				"root before", "user before", "id before",
+				"handler",
+				"id after", "user after", "root after",
+				"server2 after", "server1 after",
+			},
+		},
+		{
+			name: "order",
+			path: "/order",
+			wantLogs: []string{
+				"server1 before", "server2 before",
+				"root before",
+				"handler",
+				"root after",
+				"server2 after", "server1 after",
+			},
+		},
+		{
+			// 没有命中也会执行服务器级别的 Middleware
+			name: "not found",
+			path: "/abc",
+			wantLogs: []string{
+				"server1 before", "server2 before",
+				"server2 after", "server1 after",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs = nil
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.wantLogs, logs)
+		})
+	}
+}
+
+func TestHTTPServer_flushResp(t *testing.T) {
+	// Middleware 在 handler 之后改写响应
+	rewrite := func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			if ctx.RespStatusCode == http.StatusNotFound {
+				ctx.RespData = []byte("page not found")
+			}
+		}
+	}
+	h := NewHTTPServer(ServerWithMiddleware(rewrite))
+	h.Get("/user", func(ctx *Context) {
+		ctx.RespString(http.StatusOK, "hello, user")
+	})
+	h.Get("/raw", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("hello, raw"))
+	})
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "resp string",
+			path:     "/user",
+			wantCode: http.StatusOK,
+			wantBody: "hello, user",
+		},
+		{
+			// 直接写 ctx.Resp 也是可以的
+			name:     "raw",
+			path:     "/raw",
+			wantCode: http.StatusOK,
+			wantBody: "hello, raw",
+		},
+		{
+			name:     "not found",
+			path:     "/order",
+			wantCode: http.StatusNotFound,
+			wantBody: "page not found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestHTTPServer_MethodNotAllowed(t *testing.T) {
+	h := NewHTTPServer()
+	h.Get("/user", func(ctx *Context) {
+		ctx.RespString(http.StatusOK, "hello, user")
+	})
+	h.POST("/user", func(ctx *Context) {
+		ctx.RespString(http.StatusOK, "create user")
+	})
+	h.DELETE("/order/:id", func(ctx *Context) {
+		ctx.RespString(http.StatusOK, "delete order")
+	})
+	h.OPTIONS("/order/:id", func(ctx *Context) {
+		ctx.RespString(http.StatusOK, "order options")
+	})
+
+	testCases := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantBody  string
+		wantAllow string
+	}{
+		{
+			name:      "method not allowed",
+			method:    http.MethodPut,
+			path:      "/user",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantBody:  "METHOD NOT ALLOWED",
+			wantAllow: "GET, HEAD, OPTIONS, POST",
+		},
+		{
+			// 没有注册 GET，也就不会有 HEAD
+			name:      "method not allowed without get",
+			method:    http.MethodGet,
+			path:      "/order/123",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantBody:  "METHOD NOT ALLOWED",
+			wantAllow: "DELETE, OPTIONS",
+		},
+		{
+			name:     "not found",
+			method:   http.MethodPut,
+			path:     "/order",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+		{
+			// HEAD 使用 GET 的 handler，但是没有响应体
+			name:     "head",
+			method:   http.MethodHead,
+			path:     "/user",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "auto options",
+			method:    http.MethodOptions,
+			path:      "/user",
+			wantCode:  http.StatusNoContent,
+			wantAllow: "GET, HEAD, OPTIONS, POST",
+		},
+		{
+			// 用户注册了 OPTIONS，就用用户的
+			name:     "explicit options",
+			method:   http.MethodOptions,
+			path:     "/order/123",
+			wantCode: http.StatusOK,
+			wantBody: "order options",
+		},
+		{
+			name:     "options not found",
+			method:   http.MethodOptions,
+			path:     "/order",
+			wantCode: http.StatusNotFound,
+			wantBody: "NOT FOUND",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantAllow, recorder.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestHTTPServer_Shutdown(t *testing.T) {
+	// 先找一个空闲的端口
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	var logs []string
+	hook := func(name string) Hook {
+		return func(ctx context.Context) error {
+			logs = append(logs, name)
+			return nil
+		}
+	}
+	started := make(chan struct{})
+	h := NewHTTPServer()
+	h.OnStart(hook("start1"), hook("start2"), func(ctx context.Context) error {
+		close(started)
+		return nil
+	})
+	h.OnShutdown(hook("shutdown1"), func(ctx context.Context) error {
+		logs = append(logs, "shutdown2")
+		return errors.New("shutdown2 error")
+	}, hook("shutdown3"))
+
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	h.Get("/slow", func(ctx *Context) {
+		close(handling)
+		<-release
+		ctx.RespString(http.StatusOK, "slow")
+	})
+
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- h.Start(addr)
+	}()
+	<-started
+
+	type result struct {
+		body string
+		err  error
+	}
+	respCh := make(chan result, 1)
+	go func() {
+		resp, er := http.Get("http://" + addr + "/slow")
+		if er != nil {
+			respCh <- result{err: er}
+			return
+		}
+		defer resp.Body.Close()
+		body, er := io.ReadAll(resp.Body)
+		respCh <- result{body: string(body), err: er}
+	}()
+	<-handling
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- h.Shutdown(context.Background())
+	}()
+	// 请求还没有处理完，Shutdown 不会返回
+	select {
+	case <-shutdownErr:
+		t.Fatal("Shutdown 没有等待请求处理完毕")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+
+	res := <-respCh
+	require.NoError(t, res.err)
+	assert.Equal(t, "slow", res.body)
+	assert.Equal(t, errors.New("shutdown2 error"), <-shutdownErr)
+	assert.NoError(t, <-startErr)
+	assert.Equal(t, []string{"start1", "start2", "shutdown1", "shutdown2", "shutdown3"}, logs)
+}
+
+func TestHTTPServer_ShutdownOrder(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	h := NewHTTPServer()
+	h.Get("/ping", func(ctx *Context) {
+		ctx.RespString(http.StatusOK, "pong")
+	})
+	started := make(chan struct{})
+	h.OnStart(func(ctx context.Context) error {
+		close(started)
+		return nil
+	})
+	var logs []string
+	h.OnShutdown(func(ctx context.Context) error {
+		// 从注册中心注销的时候，服务器还在处理请求
+		resp, er := http.Get("http://" + addr + "/ping")
+		if er != nil {
+			return er
+		}
+		_ = resp.Body.Close()
+		logs = append(logs, "shutdown")
+		return nil
+	})
+	h.OnStop(func(ctx context.Context) error {
+		// 已经不再接收新的连接了
+		if conn, er := net.Dial("tcp", addr); er == nil {
+			_ = conn.Close()
+			return errors.New("服务器还在接收连接")
+		}
+		logs = append(logs, "stop")
+		return errors.New("stop error")
+	})
+
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- h.Start(addr)
+	}()
+	<-started
+	assert.Equal(t, errors.New("stop error"), h.Shutdown(context.Background()))
+	assert.NoError(t, <-startErr)
+	assert.Equal(t, []string{"shutdown", "stop"}, logs)
+}
+
+func TestHTTPServer_StartHookError(t *testing.T) {
+	h := NewHTTPServer()
+	h.OnStart(func(ctx context.Context) error {
+		return errors.New("register error")
+	})
+	err := h.Start("127.0.0.1:0")
+	assert.Equal(t, errors.New("register error"), err)
+}
+
+func TestHTTPServer_ContextReuse(t *testing.T) {
+	var params Params
+	var userValues map[string]any
+	h := NewHTTPServer()
+	h.Get("/user/:id", func(ctx *Context) {
+		ctx.UserValues = map[string]any{"id": ctx.PathParams}
+		ctx.RespStatusCode = http.StatusAccepted
+	})
+	h.Get("/order", func(ctx *Context) {
+		params = ctx.PathParams
+		userValues = ctx.UserValues
+	})
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/123", nil))
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/order", nil))
+	// 复用的 Context 不会带着上一个请求的数据
+	assert.Empty(t, params)
+	assert.Nil(t, userValues)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,70 @@
+package cookie
+
+import (
+	"net/http"
+)
+
+// Propagator 使用 cookie 传递 session id
+type Propagator struct {
+	cookieName string
+	// cookieOpt 定制 cookie，例如设置 Domain、Secure
+	cookieOpt func(c *http.Cookie)
+}
+
+type PropagatorOption func(p *Propagator)
+
+func NewPropagator(opts ...PropagatorOption) *Propagator {
+	res := &Propagator{
+		cookieName: "sessid",
+		cookieOpt: func(c *http.Cookie) {
+			c.HttpOnly = true
+		},
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+func WithCookieName(name string) PropagatorOption {
+	return func(p *Propagator) {
+		p.cookieName = name
+	}
+}
+
+func WithCookieOption(opt func(c *http.Cookie)) PropagatorOption {
+	return func(p *Propagator) {
+		p.cookieOpt = opt
+	}
+}
+
+func (p *Propagator) Inject(id string, writer http.ResponseWriter) error {
+	c := &http.Cookie{
+		Name:  p.cookieName,
+		Value: id,
+		Path:  "/",
+	}
+	p.cookieOpt(c)
+	http.SetCookie(writer, c)
+	return nil
+}
+
+func (p *Propagator) Extract(req *http.Request) (string, error) {
+	c, err := req.Cookie(p.cookieName)
+	if err != nil {
+		return "", err
+	}
+	return c.Value, nil
+}
+
+func (p *Propagator) Remove(writer http.ResponseWriter) error {
+	c := &http.Cookie{
+		Name: p.cookieName,
+		Path: "/",
+	}
+	p.cookieOpt(c)
+	// 放在 cookieOpt 之后，确保 cookie 一定会被删除
+	c.MaxAge = -1
+	http.SetCookie(writer, c)
+	return nil
+}
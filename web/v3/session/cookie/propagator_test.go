@@ -0,0 +1,41 @@
+package cookie
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPropagator(t *testing.T) {
+	p := NewPropagator(WithCookieName("my_sess"), WithCookieOption(func(c *http.Cookie) {
+		c.Secure = true
+		c.MaxAge = 3600
+	}))
+
+	recorder := httptest.NewRecorder()
+	require.NoError(t, p.Inject("sess-1", recorder))
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "my_sess", cookies[0].Name)
+	assert.Equal(t, "sess-1", cookies[0].Value)
+	assert.True(t, cookies[0].Secure)
+	assert.Equal(t, 3600, cookies[0].MaxAge)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := p.Extract(req)
+	assert.Equal(t, http.ErrNoCookie, err)
+	req.AddCookie(cookies[0])
+	id, err := p.Extract(req)
+	require.NoError(t, err)
+	assert.Equal(t, "sess-1", id)
+
+	// 即便 cookieOpt 设置了 MaxAge，也会被删除
+	recorder = httptest.NewRecorder()
+	require.NoError(t, p.Remove(recorder))
+	cookies = recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "my_sess", cookies[0].Name)
+	assert.Equal(t, -1, cookies[0].MaxAge)
+}
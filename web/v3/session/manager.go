@@ -0,0 +1,81 @@
+package session
+
+import (
+	web "leanring-go/web/v3"
+)
+
+// Manager 用户友好的门面，把 Store 和 Propagator 结合起来
+// 同一个请求里面的 Session 会缓存在 web.Context 上
+type Manager struct {
+	Store
+	Propagator
+	// SessCtxKey Session 在 web.Context.UserValues 里面的 key
+	SessCtxKey string
+}
+
+// GetSession 从 ctx 里面拿到 Session
+// 第一次获取之后会缓存下来，后面就不用再查询 Store 了
+func (m *Manager) GetSession(ctx *web.Context) (Session, error) {
+	if ctx.UserValues == nil {
+		ctx.UserValues = make(map[string]any, 1)
+	}
+	val, ok := ctx.UserValues[m.SessCtxKey]
+	if ok {
+		return val.(Session), nil
+	}
+	id, err := m.Extract(ctx.Req)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := m.Get(ctx.Req.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	ctx.UserValues[m.SessCtxKey] = sess
+	return sess, nil
+}
+
+// InitSession 初始化一个 Session，并且注入到响应里面
+// 一般在登录成功之后调用
+func (m *Manager) InitSession(ctx *web.Context, id string) (Session, error) {
+	sess, err := m.Generate(ctx.Req.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Inject(id, ctx.Resp); err != nil {
+		return nil, err
+	}
+	if ctx.UserValues == nil {
+		ctx.UserValues = make(map[string]any, 1)
+	}
+	ctx.UserValues[m.SessCtxKey] = sess
+	return sess, nil
+}
+
+// RefreshSession 刷新 Session 的过期时间
+func (m *Manager) RefreshSession(ctx *web.Context) (Session, error) {
+	sess, err := m.GetSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Refresh(ctx.Req.Context(), sess.ID()); err != nil {
+		return nil, err
+	}
+	if err = m.Inject(sess.ID(), ctx.Resp); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// RemoveSession 删除 Session，一般在退出登录的时候调用
+func (m *Manager) RemoveSession(ctx *web.Context) error {
+	sess, err := m.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+	if err = m.Store.Remove(ctx.Req.Context(), sess.ID()); err != nil {
+		return err
+	}
+	delete(ctx.UserValues, m.SessCtxKey)
+	return m.Propagator.Remove(ctx.Resp)
+}
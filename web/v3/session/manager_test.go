@@ -0,0 +1,87 @@
+package session_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	web "leanring-go/web/v3"
+	"leanring-go/web/v3/session"
+	"leanring-go/web/v3/session/cookie"
+	"leanring-go/web/v3/session/memory"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	store := memory.NewStore(time.Minute)
+	defer store.Close()
+	m := &session.Manager{
+		Store:      store,
+		Propagator: cookie.NewPropagator(),
+		SessCtxKey: "_sess",
+	}
+
+	h := web.NewHTTPServer()
+	h.POST("/login", func(ctx *web.Context) {
+		sess, err := m.InitSession(ctx, "sess-1")
+		if err != nil {
+			ctx.RespString(http.StatusInternalServerError, err.Error())
+			return
+		}
+		_ = sess.Set(ctx.Req.Context(), "name", "Tom")
+		ctx.RespString(http.StatusOK, "login")
+	})
+	h.Get("/user", func(ctx *web.Context) {
+		sess, err := m.GetSession(ctx)
+		if err != nil {
+			ctx.RespString(http.StatusUnauthorized, "请重新登录")
+			return
+		}
+		// 同一个请求里面会缓存 session
+		cached, _ := m.GetSession(ctx)
+		if cached != sess {
+			ctx.RespString(http.StatusInternalServerError, "没有缓存 session")
+			return
+		}
+		name, _ := sess.Get(ctx.Req.Context(), "name")
+		ctx.RespString(http.StatusOK, name.(string))
+	})
+	h.POST("/logout", func(ctx *web.Context) {
+		if err := m.RemoveSession(ctx); err != nil {
+			ctx.RespString(http.StatusUnauthorized, "请重新登录")
+			return
+		}
+		ctx.RespString(http.StatusOK, "logout")
+	})
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user", nil))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/login", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.AddCookie(cookies[0])
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "Tom", recorder.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(cookies[0])
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, -1, recorder.Result().Cookies()[0].MaxAge)
+
+	req = httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.AddCookie(cookies[0])
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"leanring-go/web/v3/session"
+	"sync"
+	"time"
+)
+
+var _ session.Store = &Store{}
+
+// Store 基于内存的 session.Store
+// 过期的 session 在访问的时候删除，同时有一个后台 goroutine 定期清理
+type Store struct {
+	mutex      sync.RWMutex
+	sessions   map[string]*memorySession
+	expiration time.Duration
+	close      chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewStore 创建一个 Store
+// expiration 是 session 的过期时间
+// 不再使用的时候需要调用 Close 停止后台清理
+func NewStore(expiration time.Duration) *Store {
+	res := &Store{
+		sessions:   make(map[string]*memorySession, 16),
+		expiration: expiration,
+		close:      make(chan struct{}),
+	}
+	go res.cleanup(expiration)
+	return res
+}
+
+func (s *Store) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.mutex.Lock()
+			for id, sess := range s.sessions {
+				if sess.expired(now) {
+					delete(s.sessions, id)
+				}
+			}
+			s.mutex.Unlock()
+		case <-s.close:
+			return
+		}
+	}
+}
+
+func (s *Store) Generate(ctx context.Context, id string) (session.Session, error) {
+	sess := &memorySession{
+		id:       id,
+		values:   make(map[string]any, 4),
+		deadline: time.Now().Add(s.expiration),
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions[id] = sess
+	return sess, nil
+}
+
+func (s *Store) Refresh(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sess, ok := s.sessions[id]
+	now := time.Now()
+	if !ok || sess.expired(now) {
+		delete(s.sessions, id)
+		return session.ErrSessionNotFound
+	}
+	sess.refresh(now.Add(s.expiration))
+	return nil
+}
+
+func (s *Store) Remove(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, id string) (session.Session, error) {
+	s.mutex.RLock()
+	sess, ok := s.sessions[id]
+	s.mutex.RUnlock()
+	if !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	if sess.expired(time.Now()) {
+		s.mutex.Lock()
+		// double check，避免删掉了别人刚刚生成的 session
+		if cur, ok := s.sessions[id]; ok && cur == sess {
+			delete(s.sessions, id)
+		}
+		s.mutex.Unlock()
+		return nil, session.ErrSessionNotFound
+	}
+	return sess, nil
+}
+
+// Close 停止后台清理
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.close)
+	})
+	return nil
+}
+
+type memorySession struct {
+	mutex    sync.RWMutex
+	id       string
+	values   map[string]any
+	deadline time.Time
+}
+
+func (m *memorySession) Get(ctx context.Context, key string) (any, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	val, ok := m.values[key]
+	if !ok {
+		return nil, session.ErrKeyNotFound
+	}
+	return val, nil
+}
+
+func (m *memorySession) Set(ctx context.Context, key string, val any) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.values[key] = val
+	return nil
+}
+
+func (m *memorySession) ID() string {
+	return m.id
+}
+
+func (m *memorySession) expired(now time.Time) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return now.After(m.deadline)
+}
+
+func (m *memorySession) refresh(deadline time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.deadline = deadline
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"leanring-go/web/v3/session"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(time.Millisecond * 100)
+	defer store.Close()
+	ctx := context.Background()
+
+	sess, err := store.Generate(ctx, "sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, "sess-1", sess.ID())
+	require.NoError(t, sess.Set(ctx, "user_id", 123))
+
+	sess, err = store.Get(ctx, "sess-1")
+	require.NoError(t, err)
+	val, err := sess.Get(ctx, "user_id")
+	require.NoError(t, err)
+	assert.Equal(t, 123, val)
+	_, err = sess.Get(ctx, "name")
+	assert.Equal(t, session.ErrKeyNotFound, err)
+
+	// 刷新之后还没有过期
+	time.Sleep(time.Millisecond * 60)
+	require.NoError(t, store.Refresh(ctx, "sess-1"))
+	time.Sleep(time.Millisecond * 60)
+	_, err = store.Get(ctx, "sess-1")
+	require.NoError(t, err)
+
+	// 过期了
+	time.Sleep(time.Millisecond * 60)
+	_, err = store.Get(ctx, "sess-1")
+	assert.Equal(t, session.ErrSessionNotFound, err)
+	assert.Equal(t, session.ErrSessionNotFound, store.Refresh(ctx, "sess-1"))
+
+	_, err = store.Generate(ctx, "sess-2")
+	require.NoError(t, err)
+	require.NoError(t, store.Remove(ctx, "sess-2"))
+	_, err = store.Get(ctx, "sess-2")
+	assert.Equal(t, session.ErrSessionNotFound, err)
+}
+
+func TestStore_cleanup(t *testing.T) {
+	store := NewStore(time.Millisecond * 50)
+	defer store.Close()
+	_, err := store.Generate(context.Background(), "sess-1")
+	require.NoError(t, err)
+
+	// 没有访问也会被清理掉
+	time.Sleep(time.Millisecond * 150)
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	assert.Equal(t, 0, len(store.sessions))
+}
@@ -0,0 +1,3 @@
+-- 用一个字段占位，这样即便 session 里面没有数据，key 也是存在的
+redis.call('hset', KEYS[1], ARGV[1], ARGV[2])
+return redis.call('pexpire', KEYS[1], ARGV[3])
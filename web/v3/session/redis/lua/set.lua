@@ -0,0 +1,7 @@
+-- session 不存在或者已经过期，就不能再设置值
+if redis.call('exists', KEYS[1]) == 1 then
+    redis.call('hset', KEYS[1], ARGV[1], ARGV[2])
+    return 1
+else
+    return 0
+end
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"leanring-go/web/v3/session"
+	"time"
+)
+
+var (
+	//go:embed lua/generate.lua
+	luaGenerate string
+	//go:embed lua/set.lua
+	luaSet string
+)
+
+// placeholderKey 占位字段，session 内部使用
+const placeholderKey = "_sess_id"
+
+var _ session.Store = &Store{}
+
+// Store 基于 Redis 的 session.Store
+// 每一个 session 对应一个 hash，值都会被转成字符串
+type Store struct {
+	client     redis.Cmdable
+	prefix     string
+	expiration time.Duration
+}
+
+type StoreOption func(s *Store)
+
+func NewStore(client redis.Cmdable, opts ...StoreOption) *Store {
+	res := &Store{
+		client:     client,
+		prefix:     "sessid",
+		expiration: time.Minute * 15,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// StoreWithPrefix 设置 key 的前缀，默认是 sessid
+func StoreWithPrefix(prefix string) StoreOption {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// StoreWithExpiration 设置过期时间，默认是十五分钟
+func StoreWithExpiration(expiration time.Duration) StoreOption {
+	return func(s *Store) {
+		s.expiration = expiration
+	}
+}
+
+func (s *Store) Generate(ctx context.Context, id string) (session.Session, error) {
+	key := s.key(id)
+	_, err := s.client.Eval(ctx, luaGenerate, []string{key},
+		placeholderKey, id, s.expiration.Milliseconds()).Result()
+	if err != nil {
+		return nil, err
+	}
+	return &redisSession{
+		key:    key,
+		id:     id,
+		client: s.client,
+	}, nil
+}
+
+func (s *Store) Refresh(ctx context.Context, id string) error {
+	ok, err := s.client.Expire(ctx, s.key(id), s.expiration).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return session.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *Store) Remove(ctx context.Context, id string) error {
+	_, err := s.client.Del(ctx, s.key(id)).Result()
+	return err
+}
+
+func (s *Store) Get(ctx context.Context, id string) (session.Session, error) {
+	key := s.key(id)
+	cnt, err := s.client.Exists(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if cnt != 1 {
+		return nil, session.ErrSessionNotFound
+	}
+	return &redisSession{
+		key:    key,
+		id:     id,
+		client: s.client,
+	}, nil
+}
+
+func (s *Store) key(id string) string {
+	return fmt.Sprintf("%s:%s", s.prefix, id)
+}
+
+type redisSession struct {
+	key    string
+	id     string
+	client redis.Cmdable
+}
+
+// Get 返回的值都是 string
+func (r *redisSession) Get(ctx context.Context, key string) (any, error) {
+	val, err := r.client.HGet(ctx, r.key, key).Result()
+	if err == redis.Nil {
+		return nil, session.ErrKeyNotFound
+	}
+	return val, err
+}
+
+func (r *redisSession) Set(ctx context.Context, key string, val any) error {
+	res, err := r.client.Eval(ctx, luaSet, []string{r.key}, key, val).Int()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return session.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *redisSession) ID() string {
+	return r.id
+}
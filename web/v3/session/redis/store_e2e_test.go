@@ -0,0 +1,53 @@
+//go:build e2e
+
+package redis
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"leanring-go/web/v3/session"
+	"testing"
+	"time"
+)
+
+func TestStore_e2e(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	store := NewStore(rdb, StoreWithExpiration(time.Second*2))
+	ctx := context.Background()
+
+	sess, err := store.Generate(ctx, "sess-1")
+	require.NoError(t, err)
+	require.NoError(t, sess.Set(ctx, "user_id", 123))
+
+	sess, err = store.Get(ctx, "sess-1")
+	require.NoError(t, err)
+	val, err := sess.Get(ctx, "user_id")
+	require.NoError(t, err)
+	// redis 里面的值都是字符串
+	assert.Equal(t, "123", val)
+	_, err = sess.Get(ctx, "name")
+	assert.Equal(t, session.ErrKeyNotFound, err)
+
+	time.Sleep(time.Second)
+	require.NoError(t, store.Refresh(ctx, "sess-1"))
+	time.Sleep(time.Second + time.Millisecond*500)
+	_, err = store.Get(ctx, "sess-1")
+	require.NoError(t, err)
+
+	// 过期了
+	time.Sleep(time.Second)
+	_, err = store.Get(ctx, "sess-1")
+	assert.Equal(t, session.ErrSessionNotFound, err)
+	assert.Equal(t, session.ErrSessionNotFound, sess.Set(ctx, "user_id", 456))
+	assert.Equal(t, session.ErrSessionNotFound, store.Refresh(ctx, "sess-1"))
+
+	_, err = store.Generate(ctx, "sess-2")
+	require.NoError(t, err)
+	require.NoError(t, store.Remove(ctx, "sess-2"))
+	_, err = store.Get(ctx, "sess-2")
+	assert.Equal(t, session.ErrSessionNotFound, err)
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrKeyNotFound session 里面没有这个 key
+	ErrKeyNotFound = errors.New("session: 找不到 key")
+	// ErrSessionNotFound session 不存在或者已经过期
+	ErrSessionNotFound = errors.New("session: 找不到 session")
+)
+
+// Store 管理 Session 本身
+type Store interface {
+	// Generate 生成一个新的 session
+	Generate(ctx context.Context, id string) (Session, error)
+	// Refresh 刷新过期时间
+	Refresh(ctx context.Context, id string) error
+	Remove(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (Session, error)
+}
+
+type Session interface {
+	Get(ctx context.Context, key string) (any, error)
+	Set(ctx context.Context, key string, val any) error
+	ID() string
+}
+
+// Propagator 在请求和响应之间传递 session id
+type Propagator interface {
+	// Inject 将 session id 注入到响应里面
+	Inject(id string, writer http.ResponseWriter) error
+	// Extract 从请求里面提取出 session id
+	Extract(req *http.Request) (string, error)
+	// Remove 将 session id 从响应里面删除
+	Remove(writer http.ResponseWriter) error
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"io/fs"
+)
+
+// TemplateEngine 模板引擎
+// 渲染的结果一次性返回，由框架统一写回响应，
+// 这样渲染失败的时候不会把一半的页面写给客户端
+type TemplateEngine interface {
+	// Render 渲染页面
+	// tplName 模板的名字，按名索引
+	// data 渲染页面用的数据
+	Render(ctx context.Context, tplName string, data any) ([]byte, error)
+}
+
+// ServerWithTemplateEngine 设置模板引擎
+func ServerWithTemplateEngine(tplEngine TemplateEngine) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.tplEngine = tplEngine
+	}
+}
+
+// GoTemplateEngine 基于 html/template 的默认实现
+type GoTemplateEngine struct {
+	T *template.Template
+}
+
+func (g *GoTemplateEngine) Render(ctx context.Context, tplName string, data any) ([]byte, error) {
+	// 还没有加载模板，直接调用 ExecuteTemplate 会 panic
+	if g.T == nil {
+		return nil, errors.New("web: 没有加载模板")
+	}
+	bs := &bytes.Buffer{}
+	err := g.T.ExecuteTemplate(bs, tplName, data)
+	return bs.Bytes(), err
+}
+
+// LoadFromGlob 加载匹配 pattern 的所有模板文件
+func (g *GoTemplateEngine) LoadFromGlob(pattern string) error {
+	var err error
+	g.T, err = template.ParseGlob(pattern)
+	return err
+}
+
+// LoadFromFiles 加载指定的模板文件
+func (g *GoTemplateEngine) LoadFromFiles(filenames ...string) error {
+	var err error
+	g.T, err = template.ParseFiles(filenames...)
+	return err
+}
+
+// LoadFromFS 从 fs.FS 里面加载匹配 patterns 的模板文件，
+// 一般配合 embed.FS 使用
+func (g *GoTemplateEngine) LoadFromFS(fsys fs.FS, patterns ...string) error {
+	var err error
+	g.T, err = template.ParseFS(fsys, patterns...)
+	return err
+}
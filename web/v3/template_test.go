@@ -0,0 +1,110 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGoTemplateEngine_LoadFromGlob(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "login.gohtml"),
+		[]byte(`{{define "login"}}<h1>{{.Name}}</h1>{{end}}`), 0644)
+	require.NoError(t, err)
+
+	tpl := &GoTemplateEngine{}
+	require.NoError(t, tpl.LoadFromGlob(filepath.Join(dir, "*.gohtml")))
+	page, err := tpl.Render(context.Background(), "login", map[string]string{"Name": "<Tom>"})
+	require.NoError(t, err)
+	// html/template 会转义
+	assert.Equal(t, "<h1>&lt;Tom&gt;</h1>", string(page))
+}
+
+func TestContext_Render(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tpls/user.gohtml": &fstest.MapFile{
+			Data: []byte(`{{define "user"}}<p>{{.Name}}</p>{{.Age.Missing}}{{end}}`),
+		},
+		"tpls/hello.gohtml": &fstest.MapFile{
+			Data: []byte(`{{define "hello"}}<p>hello, {{.}}</p>{{end}}`),
+		},
+	}
+	tpl := &GoTemplateEngine{}
+	require.NoError(t, tpl.LoadFromFS(fsys, "tpls/*.gohtml"))
+
+	h := NewHTTPServer(ServerWithTemplateEngine(tpl))
+	h.Get("/hello", func(ctx *Context) {
+		_ = ctx.Render("hello", "Tom")
+	})
+	h.Get("/user", func(ctx *Context) {
+		_ = ctx.Render("user", map[string]any{"Name": "Tom", "Age": 18})
+	})
+	h.Get("/unknown", func(ctx *Context) {
+		_ = ctx.Render("unknown", nil)
+	})
+
+	testCases := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "hello",
+			path:     "/hello",
+			wantCode: http.StatusOK,
+			wantBody: "<p>hello, Tom</p>",
+		},
+		{
+			// 渲染到一半出错，不会返回半个页面
+			name:     "execute error",
+			path:     "/user",
+			wantCode: http.StatusInternalServerError,
+			wantBody: "INTERNAL SERVER ERROR",
+		},
+		{
+			name:     "unknown template",
+			path:     "/unknown",
+			wantCode: http.StatusInternalServerError,
+			wantBody: "INTERNAL SERVER ERROR",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			h.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+
+	ctx := &Context{Req: httptest.NewRequest(http.MethodGet, "/", nil)}
+	err := ctx.Render("hello", nil)
+	assert.Equal(t, errors.New("web: 没有设置模板引擎"), err)
+	assert.Equal(t, http.StatusInternalServerError, ctx.RespStatusCode)
+}
+
+func TestContext_RenderNotLoaded(t *testing.T) {
+	// 没有加载任何模板
+	tpl := &GoTemplateEngine{}
+	_, err := tpl.Render(context.Background(), "hello", nil)
+	assert.Equal(t, errors.New("web: 没有加载模板"), err)
+
+	h := NewHTTPServer(ServerWithTemplateEngine(tpl))
+	h.Get("/hello", func(ctx *Context) {
+		err := ctx.Render("hello", "Tom")
+		assert.Equal(t, errors.New("web: 没有加载模板"), err)
+	})
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "INTERNAL SERVER ERROR", recorder.Body.String())
+}
@@ -0,0 +1,151 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StartTLS 启动 HTTPS 服务器，会一直阻塞直到服务器退出
+// certFile 和 keyFile 是 PEM 格式的证书和私钥，文件变化之后会自动重新加载，不需要重启
+// 需要双向认证的话使用 StartTLSConfig
+func (h *HTTPServer) StartTLS(addr string, certFile string, keyFile string) error {
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	return h.StartTLSConfig(addr, &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	})
+}
+
+// StartTLSConfig 使用自定义的 tls.Config 启动 HTTPS 服务器，会一直阻塞直到服务器退出
+// cfg 至少要设置 Certificates 或者 GetCertificate，需要自动重新加载证书的话使用 CertReloader：
+//
+//	reloader, err := web.NewCertReloader(certFile, keyFile)
+//	cfg := &tls.Config{
+//		GetCertificate: reloader.GetCertificate,
+//		// 双向认证，handler 里面通过 Context.ClientCert 拿到客户端证书
+//		ClientAuth: tls.RequireAndVerifyClientCert,
+//		ClientCAs:  caPool,
+//	}
+//
+// 会自动启用 HTTP/2，除非 cfg.NextProtos 里面只有 http/1.1
+func (h *HTTPServer) StartTLSConfig(addr string, cfg *tls.Config) error {
+	if cfg == nil || (len(cfg.Certificates) == 0 && cfg.GetCertificate == nil) {
+		return errors.New("web: tls.Config 至少要设置 Certificates 或者 GetCertificate")
+	}
+	// 复制一份，避免修改用户的 tls.Config
+	h.server.TLSConfig = cfg.Clone()
+	l, err := h.listen(addr)
+	if err != nil {
+		return err
+	}
+	// ServeTLS 会在 NextProtos 里面加上 h2 和 http/1.1，也就是启用了 HTTP/2
+	// 证书已经在 TLSConfig 里面了，所以不需要传文件
+	return serveErr(h.server.ServeTLS(l, "", ""))
+}
+
+// ClientCert 返回客户端证书
+// 只有开启了双向认证并且客户端提供了证书的时候才有，否则返回 nil
+// 完整的证书链在 Req.TLS.PeerCertificates 里面
+func (c *Context) ClientCert() *x509.Certificate {
+	if c.Req.TLS == nil || len(c.Req.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return c.Req.TLS.PeerCertificates[0]
+}
+
+// CertReloader 在证书文件变化之后自动重新加载证书
+// 在 TLS 握手的时候检查文件的修改时间和大小，最多每秒检查一次，所以不需要额外的 goroutine
+// 重新加载失败的时候继续使用旧的证书，下一次检查的时候再试，
+// 例如证书和私钥是分开写入的，只写完证书的时候两者不匹配
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	// 检查文件的间隔
+	interval time.Duration
+	// 上一次检查的时间
+	lastCheck int64
+
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	certStat fileStat
+	keyStat  fileStat
+}
+
+type fileStat struct {
+	// 修改时间，UnixNano
+	modTime int64
+	size    int64
+}
+
+// NewCertReloader 创建 CertReloader，证书加载失败的时候返回 error
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	res := &CertReloader{
+		certFile:  certFile,
+		keyFile:   keyFile,
+		interval:  time.Second,
+		lastCheck: time.Now().UnixNano(),
+	}
+	if err := res.reload(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetCertificate 用作 tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&r.lastCheck)
+	if now-last >= int64(r.interval) && atomic.CompareAndSwapInt64(&r.lastCheck, last, now) {
+		if err := r.reload(); err != nil {
+			log.Printf("web: 重新加载证书失败 %v", err)
+		}
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, nil
+}
+
+// reload 证书或者私钥文件变化了才重新加载
+func (r *CertReloader) reload() error {
+	certStat, err := statFile(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyStat, err := statFile(r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mutex.RLock()
+	changed := r.cert == nil || certStat != r.certStat || keyStat != r.keyStat
+	r.mutex.RUnlock()
+	if !changed {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	r.cert = &cert
+	r.certStat = certStat
+	r.keyStat = keyStat
+	r.mutex.Unlock()
+	return nil
+}
+
+func statFile(name string) (fileStat, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return fileStat{}, err
+	}
+	return fileStat{modTime: info.ModTime().UnixNano(), size: info.Size()}, nil
+}
@@ -0,0 +1,252 @@
+package web
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHTTPServer_StartTLS(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.writeCert(t, t.TempDir(), "server", true)
+
+	h := NewHTTPServer()
+	h.Get("/user", func(ctx *Context) {
+		ctx.RespString(http.StatusOK, "hello, user")
+	})
+	addr := startTLSServer(t, h, func(addr string) error {
+		return h.StartTLS(addr, certFile, keyFile)
+	})
+
+	client := ca.client(nil)
+	resp, err := client.Get("https://" + addr + "/user")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello, user", string(body))
+	// 默认启用了 HTTP/2
+	assert.Equal(t, 2, resp.ProtoMajor)
+}
+
+func TestHTTPServer_StartTLSConfig(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.writeCert(t, dir, "server", true)
+	clientCertFile, clientKeyFile := ca.writeCert(t, dir, "tom", false)
+	clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	h := NewHTTPServer()
+	h.Get("/whoami", func(ctx *Context) {
+		cert := ctx.ClientCert()
+		if cert == nil {
+			ctx.RespString(http.StatusOK, "anonymous")
+			return
+		}
+		ctx.RespString(http.StatusOK, cert.Subject.CommonName)
+	})
+	addr := startTLSServer(t, h, func(addr string) error {
+		return h.StartTLSConfig(addr, &tls.Config{
+			GetCertificate: reloader.GetCertificate,
+			ClientAuth:     tls.VerifyClientCertIfGiven,
+			ClientCAs:      ca.pool,
+		})
+	})
+
+	testCases := []struct {
+		name string
+		cert *tls.Certificate
+
+		wantBody string
+	}{
+		{
+			name:     "client cert",
+			cert:     &clientCert,
+			wantBody: "tom",
+		},
+		{
+			name:     "no client cert",
+			wantBody: "anonymous",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := ca.client(tc.cert).Get("https://" + addr + "/whoami")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantBody, string(body))
+		})
+	}
+}
+
+func TestHTTPServer_StartTLSConfigWithoutCert(t *testing.T) {
+	h := NewHTTPServer()
+	err := h.StartTLSConfig("127.0.0.1:0", &tls.Config{})
+	assert.Equal(t, errors.New("web: tls.Config 至少要设置 Certificates 或者 GetCertificate"), err)
+	err = h.StartTLS("127.0.0.1:0", "not_exist.crt", "not_exist.key")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestCertReloader_GetCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.writeCert(t, dir, "server", true)
+	reloader, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	first := cert.Certificate[0]
+
+	// 证书换了，但是还没到检查的时间
+	ca.writeCert(t, dir, "server", true)
+	touch(t, certFile, keyFile)
+	cert, err = reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, cert.Certificate[0])
+
+	// 到了检查的时间，加载新的证书
+	reloader.lastCheck = 0
+	cert, err = reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, cert.Certificate[0])
+	second := cert.Certificate[0]
+
+	// 只换了证书，私钥和证书不匹配，继续使用旧的证书
+	otherCertFile, _ := ca.writeCert(t, t.TempDir(), "other", true)
+	data, err := os.ReadFile(otherCertFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, data, 0600))
+	touch(t, certFile)
+	reloader.lastCheck = 0
+	cert, err = reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second, cert.Certificate[0])
+}
+
+// startTLSServer 在空闲的端口上启动服务器，返回监听的地址
+func startTLSServer(t *testing.T, h *HTTPServer, start func(addr string) error) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	started := make(chan struct{})
+	h.OnStart(func(ctx context.Context) error {
+		close(started)
+		return nil
+	})
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- start(addr)
+	}()
+	select {
+	case <-started:
+	case err = <-startErr:
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		assert.NoError(t, h.Shutdown(context.Background()))
+		assert.NoError(t, <-startErr)
+	})
+	return addr
+}
+
+// touch 把修改时间往后调，避免文件系统的时间精度不够导致检测不到变化
+func touch(t *testing.T, files ...string) {
+	mtime := time.Now().Add(time.Hour)
+	for _, file := range files {
+		require.NoError(t, os.Chtimes(file, mtime, mtime))
+	}
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// writeCert 签发证书，写到 dir/name.crt 和 dir/name.key
+func (ca *testCA) writeCert(t *testing.T, dir string, name string, isServer bool) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if isServer {
+		tpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+// client 信任 ca 的客户端，cert 不为 nil 的时候使用客户端证书
+func (ca *testCA) client(cert *tls.Certificate) *http.Client {
+	cfg := &tls.Config{RootCAs: ca.pool}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   cfg,
+			ForceAttemptHTTP2: true,
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"leanring-go/validate"
+	"net/http"
+)
+
+// defaultValidator 没有设置 Validator 的时候使用，根据 validate 标签校验
+var defaultValidator validate.Validator = validate.NewTagValidator()
+
+// ServerWithValidator 设置绑定请求之后使用的校验器
+// micro/rpc 的服务端也可以使用同一个校验器
+func ServerWithValidator(v validate.Validator) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.validator = v
+	}
+}
+
+// validationResp 校验不通过的时候返回的响应
+type validationResp struct {
+	Errors validate.ValidationErrors `json:"errors"`
+}
+
+// validate 校验绑定好的 val
+// 校验不通过的时候设置 400 响应，响应体是所有字段的错误
+func (c *Context) validate(val any) error {
+	v := c.validator
+	if v == nil {
+		v = defaultValidator
+	}
+	err := v.Validate(val)
+	var errs validate.ValidationErrors
+	if errors.As(err, &errs) {
+		data, er := json.Marshal(validationResp{Errors: errs})
+		if er != nil {
+			return er
+		}
+		c.Resp.Header().Set("Content-Type", "application/json")
+		c.RespStatusCode = http.StatusBadRequest
+		c.RespData = data
+	}
+	return err
+}
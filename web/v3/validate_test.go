@@ -0,0 +1,159 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"leanring-go/validate"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type createUserReq struct {
+	Name  string `json:"name" validate:"required,max=8"`
+	Email string `json:"email" validate:"omitempty,email"`
+	Role  string `json:"role" validate:"oneof=admin user"`
+}
+
+func TestContext_BindJSONValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantBody string
+		wantErr  bool
+	}{
+		{
+			name: "valid",
+			body: `{"name":"Tom","role":"admin"}`,
+		},
+		{
+			name:     "invalid",
+			body:     `{"name":"","email":"tom","role":"root"}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"errors":[` +
+				`{"field":"name","rule":"required","message":"name 不能为空"},` +
+				`{"field":"email","rule":"email","message":"email 不是合法的邮箱地址"},` +
+				`{"field":"role","rule":"oneof","param":"admin user","message":"role 必须是 [admin user] 中的一个"}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx := &Context{
+				Req:  httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)),
+				Resp: recorder,
+			}
+			err := ctx.BindJSON(&createUserReq{})
+			if !tc.wantErr {
+				assert.NoError(t, err)
+				assert.Equal(t, 0, ctx.RespStatusCode)
+				return
+			}
+			var errs validate.ValidationErrors
+			assert.True(t, errors.As(err, &errs))
+			assert.Equal(t, tc.wantCode, ctx.RespStatusCode)
+			assert.JSONEq(t, tc.wantBody, string(ctx.RespData))
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestContext_BindQuery(t *testing.T) {
+	type listReq struct {
+		Page    int      `query:"page" validate:"min=1"`
+		Size    uint8    `query:"size"`
+		Keyword string   `query:"keyword"`
+		Tags    []string `query:"tag"`
+		Asc     *bool    `query:"asc"`
+		Score   float64
+		Ignored string `query:"-"`
+	}
+	asc := true
+	testCases := []struct {
+		name     string
+		query    string
+		val      any
+		wantVal  any
+		wantErr  error
+		wantCode int
+	}{
+		{
+			name:  "all",
+			query: "page=2&size=20&keyword=go&tag=a&tag=b&asc=true&Score=1.5&Ignored=abc",
+			val:   &listReq{},
+			wantVal: &listReq{
+				Page:    2,
+				Size:    20,
+				Keyword: "go",
+				Tags:    []string{"a", "b"},
+				Asc:     &asc,
+				Score:   1.5,
+			},
+		},
+		{
+			name:    "bad format",
+			query:   "page=abc",
+			val:     &listReq{},
+			wantErr: errors.New(`web: 查询参数 page 格式错误 strconv.ParseInt: parsing "abc": invalid syntax`),
+		},
+		{
+			name:    "overflow",
+			query:   "page=1&size=256",
+			val:     &listReq{},
+			wantErr: errors.New(`web: 查询参数 size 格式错误 strconv.ParseUint: parsing "256": value out of range`),
+		},
+		{
+			name:     "validate",
+			query:    "page=0",
+			val:      &listReq{},
+			wantErr:  errors.New("validate: Page 不能小于 1"),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:    "not pointer",
+			val:     listReq{},
+			wantErr: errors.New("web: 输入必须是结构体指针"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &Context{
+				Req:  httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil),
+				Resp: httptest.NewRecorder(),
+			}
+			err := ctx.BindQuery(tc.val)
+			assert.Equal(t, tc.wantCode, ctx.RespStatusCode)
+			if tc.wantErr != nil {
+				require.Error(t, err)
+				assert.Equal(t, tc.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantVal, tc.val)
+		})
+	}
+}
+
+type mockValidator struct{}
+
+func (m mockValidator) Validate(val any) error {
+	return validate.ValidationErrors{{Field: "id", Rule: "mock", Message: "id 不合法"}}
+}
+
+func TestHTTPServer_Validator(t *testing.T) {
+	h := NewHTTPServer(ServerWithValidator(mockValidator{}))
+	h.POST("/user", func(ctx *Context) {
+		if err := ctx.BindJSON(&createUserReq{}); err != nil {
+			return
+		}
+		ctx.RespString(http.StatusOK, "ok")
+	})
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(`{"name":"Tom","role":"admin"}`)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"errors":[{"field":"id","rule":"mock","message":"id 不合法"}]}`, recorder.Body.String())
+}